@@ -0,0 +1,365 @@
+package dockermonitor_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/adrien19/dockermonitor"
+)
+
+// runningNames is a custom action implemented outside the package. It reads
+// containers collected by earlier actions and stores a derived result.
+type runningNames struct {
+	monitor *dockermonitor.DockerMonitor
+}
+
+func (a runningNames) Name() string { return "running-names" }
+
+func (a runningNames) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := a.monitor.Environment(env)
+	var names []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		if cont.State == "running" {
+			names = append(names, cont.Names)
+		}
+	}
+	return a.monitor.SetExtension(env, "runningNames", names)
+}
+
+// failing is a custom action that always fails with err.
+type failing struct {
+	err error
+}
+
+func (a failing) Name() string { return "failing" }
+
+func (a failing) Execute(ctx context.Context, env string) error { return a.err }
+
+func newTestMonitor() *dockermonitor.DockerMonitor {
+	d := dockermonitor.NewDockerMonitor([]string{"dev"})
+	d.UpdateEnvironment("dev", func(e *dockermonitor.DockerEnvironment) {
+		e.ContainersInfo = []dockermonitor.ContainerInfo{
+			{Names: "web", State: "running"},
+			{Names: "db", State: "exited"},
+		}
+	})
+	return d
+}
+
+func Example_customAction() {
+	d := newTestMonitor()
+	w := &dockermonitor.Workflow{
+		Name:    "dev",
+		Actions: []dockermonitor.Action{runningNames{monitor: d}},
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	if err := w.ExecuteActions(context.Background()); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	dockerEnv, _ := d.Environment("dev")
+	fmt.Println(w.Results[0].Action, w.Results[0].Status, string(dockerEnv.Extensions["runningNames"]))
+	// Output: running-names succeeded ["web"]
+}
+
+func TestCustomActionResults(t *testing.T) {
+	cause := errors.New("boom")
+	d := newTestMonitor()
+	w := &dockermonitor.Workflow{
+		Name: "dev",
+		Actions: []dockermonitor.Action{
+			failing{err: fmt.Errorf("probe: %w", dockermonitor.ErrUnsupported)},
+			failing{err: cause},
+			runningNames{monitor: d},
+		},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	err := w.ExecuteActions(context.Background())
+	var actionErr *dockermonitor.ActionError
+	if !errors.As(err, &actionErr) || !errors.Is(err, cause) {
+		t.Fatalf("ExecuteActions() = %v, want an ActionError wrapping %v", err, cause)
+	}
+	if actionErr.Environment != "dev" || actionErr.Action != "failing" {
+		t.Errorf("ActionError = %+v, want environment dev and action failing", actionErr)
+	}
+
+	want := []dockermonitor.ActionStatus{dockermonitor.ActionSkipped, dockermonitor.ActionFailed}
+	if len(w.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(w.Results), len(want))
+	}
+	for i, status := range want {
+		if w.Results[i].Status != status {
+			t.Errorf("Results[%d].Status = %s, want %s", i, w.Results[i].Status, status)
+		}
+	}
+}
+
+func TestProgressConcurrentWorkflows(t *testing.T) {
+	const envs = 20
+	var names []string
+	for i := 0; i < envs; i++ {
+		names = append(names, fmt.Sprintf("env-%d", i))
+	}
+	d := dockermonitor.NewDockerMonitor(names)
+	var updates []dockermonitor.ProgressUpdate
+	progress := dockermonitor.NewProgress(envs, func(u dockermonitor.ProgressUpdate) {
+		updates = append(updates, u)
+	})
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		w := &dockermonitor.Workflow{
+			Name:     name,
+			Actions:  []dockermonitor.Action{runningNames{monitor: d}},
+			Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Progress: progress,
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.ExecuteActions(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	if len(updates) != envs || progress.Completed() != envs {
+		t.Fatalf("got %d updates and %d completed, want %d", len(updates), progress.Completed(), envs)
+	}
+	for i, u := range updates {
+		if u.Completed != i+1 || u.Total != envs || u.Err != nil {
+			t.Errorf("updates[%d] = %+v", i, u)
+		}
+	}
+}
+
+func TestSelectActions(t *testing.T) {
+	d := newTestMonitor()
+	actions := []dockermonitor.Action{
+		d.CallDockerVersion(),
+		d.CallContainersStatus(),
+		d.CallLocalImages(),
+		d.CallContainerIPs(),
+	}
+
+	selected, err := dockermonitor.SelectActions(actions, "container-ips")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range selected {
+		names = append(names, a.Name())
+	}
+	if want := "containers-status,container-ips"; strings.Join(names, ",") != want {
+		t.Errorf("SelectActions(container-ips) = %v, want %s", names, want)
+	}
+
+	if _, err := dockermonitor.SelectActions(actions, "container-status"); err == nil || !strings.Contains(err.Error(), "local-images") {
+		t.Errorf("SelectActions(container-status) = %v, want an error listing the valid actions", err)
+	}
+}
+
+func TestServerRedactsEnvironments(t *testing.T) {
+	d := newTestMonitor()
+	d.UpdateEnvironment("dev", func(e *dockermonitor.DockerEnvironment) {
+		e.ContainersInfo[0].Labels = "token=s3cr3t"
+	})
+	policy, err := dockermonitor.NewRedactionPolicy(`token=(\S+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Redaction = policy
+	s := dockermonitor.NewServer(d)
+
+	for _, path := range []string{"/environments/dev", "/environments/dev/containers"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || strings.Contains(body, "s3cr3t") || !strings.Contains(body, dockermonitor.Redacted) {
+			t.Errorf("GET %s = %d %s, want the label redacted", path, rec.Code, body)
+		}
+	}
+}
+
+// collecting stores the containers that dependent reads, counting its runs.
+type collecting struct {
+	monitor *dockermonitor.DockerMonitor
+	runs    *int
+}
+
+func (a collecting) Name() string { return "collecting" }
+
+func (a collecting) Execute(ctx context.Context, env string) error {
+	*a.runs++
+	a.monitor.UpdateEnvironment(env, func(e *dockermonitor.DockerEnvironment) {
+		e.ContainersInfo = []dockermonitor.ContainerInfo{{Names: "web", State: "running"}}
+	})
+	return nil
+}
+
+// dependent is runningNames depending on collecting.
+type dependent struct {
+	runningNames
+}
+
+func (a dependent) DependsOn() []string { return []string{"collecting"} }
+
+func TestResumeRerunsDependencies(t *testing.T) {
+	state := dockermonitor.NewResumeState(t.TempDir() + "/state.json")
+	for _, action := range []string{"collecting", "failing"} {
+		if err := state.MarkDone("dev", action); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := dockermonitor.NewDockerMonitor([]string{"dev"})
+	var runs int
+	w := &dockermonitor.Workflow{
+		Name:    "dev",
+		Actions: []dockermonitor.Action{dependent{runningNames{monitor: d}}, collecting{monitor: d, runs: &runs}, failing{err: errors.New("boom")}},
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Resume:  state,
+	}
+	if err := w.ExecuteActions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if runs != 1 {
+		t.Errorf("collecting ran %d times, want 1 for its pending dependent", runs)
+	}
+	dockerEnv, _ := d.Environment("dev")
+	if got := string(dockerEnv.Extensions["runningNames"]); got != `["web"]` {
+		t.Errorf("runningNames = %s, want the resumed dependency's containers", got)
+	}
+	if last := w.Results[len(w.Results)-1]; last.Action != "failing" || last.Status != dockermonitor.ActionSkipped {
+		t.Errorf("last result = %+v, want failing skipped as already succeeded", last)
+	}
+}
+
+// newPopulatedMonitor returns a monitor with what a full run collects:
+// containers with labels and stats, and images.
+func newPopulatedMonitor() *dockermonitor.DockerMonitor {
+	d := dockermonitor.NewDockerMonitor([]string{"dev", "prod"})
+	for _, env := range []string{"dev", "prod"} {
+		d.UpdateEnvironment(env, func(e *dockermonitor.DockerEnvironment) {
+			e.ContainersInfo = []dockermonitor.ContainerInfo{
+				{ID: "0123456789ab", Names: "web", Image: "nginx:1.27", State: "running", Status: "Up 2 hours",
+					Labels: "app=shop,tier=web", Networks: "front", Ports: "0.0.0.0:80->80/tcp",
+					Stats: &dockermonitor.ContainerStats{CPUPercent: 12.5, MemoryUsageBytes: 64 << 20, MemoryLimitBytes: 1 << 30,
+						MemoryPercent: 6.25, NetRxBytes: 1024, NetTxBytes: 2048, PIDs: 4, CPUPercentAvg: 10, Samples: 3}},
+				{ID: "ba9876543210", Names: "db", Image: "postgres:16", State: "exited", Status: "Exited (0) 1 hour ago",
+					Labels: "app=shop,tier=db"},
+			}
+			e.ImagesInfo = []dockermonitor.ImageInfo{
+				{ID: "sha256:1111", Repository: "nginx", Tag: "1.27", Size: "190MB", CreatedSince: "2 weeks ago"},
+				{ID: "sha256:2222", Repository: "postgres", Tag: "16", Size: "430MB", CreatedSince: "3 weeks ago"},
+			}
+			e.RunningContainers, e.StoppedContainers, e.TotalLocalDockerImages = 1, 1, 2
+		})
+	}
+	return d
+}
+
+func TestJSONFormatterMatchesSnapshotSchema(t *testing.T) {
+	d := newPopulatedMonitor()
+	for _, f := range []dockermonitor.JSONFormatter{{}, {Compact: true}} {
+		var buf bytes.Buffer
+		if err := f.Format(&buf, d); err != nil {
+			t.Fatal(err)
+		}
+		if err := dockermonitor.ValidateSnapshot(buf.Bytes()); err != nil {
+			t.Errorf("ValidateSnapshot(%+v output) = %v, want nil", f, err)
+		}
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := []dockermonitor.DockerEnvironment{
+		{Environment: "dev", RunningContainers: 2, TotalLocalDockerImages: 1,
+			ContainersInfo: []dockermonitor.ContainerInfo{
+				{Names: "web", Image: "nginx:1.26", State: "running"},
+				{Names: "cache", Image: "redis:7", State: "running"},
+			},
+			ImagesInfo: []dockermonitor.ImageInfo{{ID: "sha256:1111", Repository: "nginx", Tag: "1.26"}}},
+		{Environment: "old"},
+	}
+	after := []dockermonitor.DockerEnvironment{
+		{Environment: "dev", RunningContainers: 2, TotalLocalDockerImages: 1,
+			ContainersInfo: []dockermonitor.ContainerInfo{
+				{Names: "web", Image: "nginx:1.27", State: "running"},
+				{Names: "db", Image: "postgres:16", State: "running"},
+			},
+			ImagesInfo: []dockermonitor.ImageInfo{{ID: "sha256:2222", Repository: "nginx", Tag: "1.27"}}},
+		{Environment: "new"},
+	}
+
+	got := dockermonitor.DiffSnapshots(before, after)
+	want := []dockermonitor.SnapshotChange{
+		{Environment: "dev", Kind: "container", Name: "db", Change: dockermonitor.ChangeAdded},
+		{Environment: "dev", Kind: "container", Name: "web", Change: dockermonitor.ChangeChanged, Field: "image", Before: "nginx:1.26", After: "nginx:1.27"},
+		{Environment: "dev", Kind: "container", Name: "cache", Change: dockermonitor.ChangeRemoved},
+		{Environment: "dev", Kind: "image", Name: "nginx:1.27", Change: dockermonitor.ChangeAdded},
+		{Environment: "dev", Kind: "image", Name: "nginx:1.26", Change: dockermonitor.ChangeRemoved},
+		{Environment: "new", Kind: "environment", Change: dockermonitor.ChangeAdded},
+		{Environment: "old", Kind: "environment", Change: dockermonitor.ChangeRemoved},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffSnapshots() =\n%v\nwant\n%v", got, want)
+	}
+	if changes := dockermonitor.DiffSnapshots(after, after); len(changes) != 0 {
+		t.Errorf("DiffSnapshots(after, after) = %v, want no changes", changes)
+	}
+}
+
+func TestSnapshotStoreLoadLatest(t *testing.T) {
+	store := dockermonitor.NewSnapshotStore(t.TempDir())
+	if _, err := store.LoadLatest(); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("LoadLatest() on an empty store = %v, want fs.ErrNotExist", err)
+	}
+
+	path, err := store.Save(newPopulatedMonitor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, ".json.gz") {
+		t.Errorf("Save() = %s, want a .json.gz file", path)
+	}
+	environments, err := store.LoadLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(environments) != 2 || len(environments[0].ContainersInfo) != 2 || environments[0].ContainersInfo[0].Stats == nil ||
+		environments[0].ContainersInfo[0].Labels != "app=shop,tier=web" {
+		t.Errorf("LoadLatest() after Save = %+v, want the saved environments", environments)
+	}
+
+	// A plain snapshot placed alongside, taken later, is the latest.
+	var buf bytes.Buffer
+	if err := (dockermonitor.JSONFormatter{}).Format(&buf, newTestMonitor()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(store.Dir, "snapshot-29991231T235959.000Z.json"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	environments, err = store.LoadLatest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(environments) != 1 || environments[0].Environment != "dev" || len(environments[0].ContainersInfo) != 2 ||
+		environments[0].ContainersInfo[0].Stats != nil {
+		t.Errorf("LoadLatest() with a later .json = %+v, want the plain snapshot", environments)
+	}
+}
@@ -0,0 +1,151 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type CheckDockerVersion struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckDockerVersion) Name() string { return "docker-version" }
+
+func (c CheckDockerVersion) Execute(ctx context.Context, env string) error {
+
+	// Commands for environments with a Host are run on the remote host over ssh,
+	// see DockerCommand.
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "--version")
+	if err != nil {
+		return err
+	}
+
+	version := string(out)
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.DockerVersion = version
+	})
+	c.dockerMonitor.logger().Info("docker version", "environment", env, "version", strings.TrimSpace(version))
+	return nil
+}
+
+type CheckContainersStatus struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainersStatus) Name() string { return "containers-status" }
+
+func (c CheckContainersStatus) Execute(ctx context.Context, env string) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "container", "ls", "-a", "--format", "{{json .}}")
+	if err != nil {
+		return err
+	}
+	containerOutput, err := parseContainers(string(out))
+	if err != nil {
+		return err
+	}
+
+	stopped := 0
+	running := 0
+	for _, cont := range containerOutput {
+		if cont.State == "exited" {
+			stopped += 1
+		} else {
+			running += 1
+		}
+	}
+
+	// containerOutput containes details of all container.
+	// This can be used to parse other related information
+	// fmt.Println(containerOutput)
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.StoppedContainers = stopped
+		e.RunningContainers = running
+		e.ContainersInfo = containerOutput
+		e.CollectedAt = time.Now().UTC()
+	})
+	c.dockerMonitor.logger().Info("containers", "environment", env, "stopped", stopped, "running", running)
+
+	return nil
+}
+
+// parseContainers decodes `docker container ls --format "{{json .}}"` output,
+// which prints one JSON object per container and line. Labels and Networks
+// are sorted, see ContainerInfo.UnmarshalJSON.
+func parseContainers(out string) ([]ContainerInfo, error) {
+	var containers []ContainerInfo
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var cont ContainerInfo
+		if err := unmarshalLine(line, "ContainerInfo", &cont); err != nil {
+			return nil, err
+		}
+		containers = append(containers, cont)
+	}
+	return containers, nil
+}
+
+type CheckLocalImages struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckLocalImages) Name() string { return "local-images" }
+
+func (c CheckLocalImages) Execute(ctx context.Context, env string) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "images", "--format", "{{json .}}")
+	if err != nil {
+		return err
+	}
+	imageOutput, err := parseImages(string(out))
+	if err != nil {
+		return err
+	}
+	// Uncomment the lines below if you want to omit native kubernetes images
+	// imageOutput = slices.DeleteFunc(imageOutput, func(img ImageInfo) bool {
+	// 	return strings.Contains(img.Repository, "k8s.gcr.io") || strings.Contains(img.Repository, "kubernetes")
+	// })
+	// An image with several tags has a row per tag but is only one image.
+	ids := make(map[string]bool)
+	for _, img := range imageOutput {
+		ids[img.ID] = true
+	}
+	totalImages := len(ids)
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.TotalLocalDockerImages = totalImages
+		e.ImagesInfo = imageOutput
+	})
+	c.dockerMonitor.logger().Info("local images", "environment", env, "total", totalImages)
+
+	return nil
+}
+
+// parseImages decodes `docker images --format "{{json .}}"` output, one JSON
+// object per image and line.
+func parseImages(out string) ([]ImageInfo, error) {
+	var images []ImageInfo
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var img ImageInfo
+		if err := unmarshalLine(line, "ImageInfo", &img); err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
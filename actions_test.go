@@ -0,0 +1,357 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseContainers(t *testing.T) {
+	out := `{"Command":"\"nginx -g 'daemon of…\"","CreatedAt":"2024-01-01 10:00:00 +0000 UTC","ID":"aaaaaaaaaaaa","Image":"nginx:latest","Labels":"team=web","LocalVolumes":"0","Mounts":"","Names":"web","Networks":"bridge","Ports":"0.0.0.0:8080->80/tcp","RunningFor":"2 hours ago","Size":"0B","State":"running","Status":"Up 2 hours"}
+{"Command":"\"docker-entrypoint.s…\"","CreatedAt":"2024-01-01 09:00:00 +0000 UTC","ID":"bbbbbbbbbbbb","Image":"postgres:15","Labels":"","LocalVolumes":"1","Mounts":"pgdata","Names":"db","Networks":"host","Ports":"","RunningFor":"3 hours ago","Size":"0B","State":"exited","Status":"Exited (0) 1 hour ago"}
+
+`
+	containers, err := parseContainers(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 2 {
+		t.Fatalf("got %d containers, want 2", len(containers))
+	}
+	if got := containers[0]; got.ID != "aaaaaaaaaaaa" || got.Names != "web" || got.State != "running" || got.Command != `"nginx -g 'daemon of…"` {
+		t.Errorf("first container = %+v", got)
+	}
+	if got := containers[1]; got.Names != "db" || got.State != "exited" || got.Mounts != "pgdata" {
+		t.Errorf("second container = %+v", got)
+	}
+}
+
+func TestParseContainersEmpty(t *testing.T) {
+	containers, err := parseContainers("\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 0 {
+		t.Errorf("got %d containers, want 0", len(containers))
+	}
+}
+
+func TestParseContainersRejectsQuotedOutput(t *testing.T) {
+	// The shape the old `--format "\"{{json .}}\""` produced.
+	line := `"{"ID":"aaaaaaaaaaaa","Names":"web"}"`
+	_, err := parseContainers(line)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("parseContainers() = %v, want a *ParseError", err)
+	}
+	if parseErr.Line != line || parseErr.Offset != 4 || parseErr.Type != "ContainerInfo" {
+		t.Errorf("ParseError = %+v", parseErr)
+	}
+}
+
+func TestParseContainersSortsMapColumns(t *testing.T) {
+	// docker ps prints labels and networks in random order.
+	first, err := parseContainers(`{"ID":"aaaaaaaaaaaa","Labels":"team=web,hosts=a,b,app=shop","Networks":"front,back"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := parseContainers(`{"ID":"aaaaaaaaaaaa","Labels":"app=shop,team=web,hosts=a,b","Networks":"back,front"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := json.Marshal(first)
+	b, _ := json.Marshal(second)
+	if string(a) != string(b) {
+		t.Errorf("%s != %s", a, b)
+	}
+	if got, want := first[0].Labels, "app=shop,hosts=a,b,team=web"; got != want {
+		t.Errorf("Labels = %q, want %q", got, want)
+	}
+	if got, want := first[0].Networks, "back,front"; got != want {
+		t.Errorf("Networks = %q, want %q", got, want)
+	}
+}
+
+func TestParseImages(t *testing.T) {
+	out := `{"Containers":"N/A","CreatedAt":"2024-01-01 00:00:00 +0000 UTC","CreatedSince":"2 weeks ago","Digest":"<none>","ID":"111111111111","Repository":"nginx","SharedSize":"N/A","Size":"187MB","Tag":"latest","UniqueSize":"N/A","VirtualSize":"187MB"}
+{"Containers":"N/A","CreatedAt":"2024-01-01 00:00:00 +0000 UTC","CreatedSince":"2 weeks ago","Digest":"<none>","ID":"222222222222","Repository":"redis","SharedSize":"N/A","Size":"100MB","Tag":"7","UniqueSize":"N/A","VirtualSize":"100MB"}
+`
+	images, err := parseImages(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("got %d images, want 2", len(images))
+	}
+	if got := images[0]; got.ID != "111111111111" || got.Repository != "nginx" || got.Tag != "latest" || got.Digest != "<none>" {
+		t.Errorf("first image = %+v", got)
+	}
+	if got := images[1]; got.Repository != "redis" || got.Tag != "7" {
+		t.Errorf("second image = %+v", got)
+	}
+}
+
+// The same container and image as printed by different docker versions.
+func TestParseAcrossDockerVersions(t *testing.T) {
+	containers := map[string]string{
+		// docker 1.13 to 19.03: no LocalVolumes, Size or State.
+		"19.03": `{"Command":"\"nginx -g 'daemon of…\"","CreatedAt":"2024-01-01 10:00:00 +0000 UTC","ID":"aaaaaaaaaaaa","Image":"nginx:1.25","Labels":"tier=web,app=shop","Mounts":"data","Names":"web","Networks":"front,back","Ports":"0.0.0.0:80->80/tcp, 443/tcp","RunningFor":"2 hours ago","Status":"Up 2 hours"}`,
+		"24.0":  `{"Command":"\"nginx -g 'daemon of…\"","CreatedAt":"2024-01-01 10:00:00 +0000 UTC","ID":"aaaaaaaaaaaa","Image":"nginx:1.25","Labels":"app=shop,tier=web","LocalVolumes":"1","Mounts":"data","Names":"web","Networks":"back,front","Ports":"0.0.0.0:80->80/tcp, 443/tcp","RunningFor":"2 hours ago","Size":"0B","State":"running","Status":"Up 2 hours"}`,
+		// docker-compatible CLIs printing the API's shapes.
+		"api": `{"Command":"\"nginx -g 'daemon of…\"","Created":1704103200,"Id":"aaaaaaaaaaaa","Image":"nginx:1.25","Labels":{"tier":"web","app":"shop"},"Mounts":["data"],"Names":["/web"],"Networks":["front","back"],"Ports":["0.0.0.0:80->80/tcp","443/tcp"],"State":"running","Status":"Up 2 hours"}`,
+	}
+	for version, out := range containers {
+		parsed, err := parseContainers(out)
+		if err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		got := parsed[0]
+		if got.ID != "aaaaaaaaaaaa" || got.Names != "web" || got.Image != "nginx:1.25" || got.Mounts != "data" ||
+			got.Labels != "app=shop,tier=web" || got.Networks != "back,front" || got.Ports != "0.0.0.0:80->80/tcp, 443/tcp" ||
+			got.CreatedAt != "2024-01-01 10:00:00 +0000 UTC" || got.Status != "Up 2 hours" {
+			t.Errorf("%s: container = %+v", version, got)
+		}
+	}
+
+	images := map[string]string{
+		"19.03": `{"Containers":"N/A","CreatedAt":"2024-01-01 00:00:00 +0000 UTC","CreatedSince":"2 weeks ago","Digest":"<none>","ID":"111111111111","Repository":"nginx","SharedSize":"N/A","Size":"187MB","Tag":"1.25","UniqueSize":"N/A","VirtualSize":"187MB"}`,
+		// docker 25 dropped VirtualSize.
+		"25.0": `{"Containers":"N/A","CreatedAt":"2024-01-01 00:00:00 +0000 UTC","CreatedSince":"2 weeks ago","Digest":"<none>","ID":"111111111111","Repository":"nginx","SharedSize":"N/A","Size":"187MB","Tag":"1.25","UniqueSize":"N/A"}`,
+		"api":  `{"Containers":-1,"Created":1704067200,"CreatedSince":"2 weeks ago","Digest":"<none>","Id":"111111111111","Repository":"nginx","Size":"187MB","Tag":"1.25"}`,
+	}
+	for version, out := range images {
+		parsed, err := parseImages(out)
+		if err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		got := parsed[0]
+		if got.ID != "111111111111" || got.Repository != "nginx" || got.Tag != "1.25" || got.Size != "187MB" ||
+			got.VirtualSize != "187MB" || got.CreatedSince != "2 weeks ago" || got.CreatedAt != "2024-01-01 00:00:00 +0000 UTC" {
+			t.Errorf("%s: image = %+v", version, got)
+		}
+	}
+}
+
+func TestCommandErrorDaemonNotRunning(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo 'Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?' >&2; exit 1")
+	_, err := cmd.Output()
+	err = commandError(cmd, err)
+	if !errors.Is(err, ErrDaemonNotRunning) || errors.Is(err, ErrHostUnreachable) {
+		t.Fatalf("commandError() = %v, want ErrDaemonNotRunning", err)
+	}
+	if !strings.Contains(err.Error(), "start it") {
+		t.Errorf("commandError() = %q, want a suggestion to start the daemon", err)
+	}
+
+	// Callers that capture stderr themselves pass it along.
+	cmd = exec.Command("sh", "-c", "echo 'Cannot connect to the Docker daemon at unix:///var/run/docker.sock.' >&2; exit 1")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err = commandErrorWithStderr(cmd, err, []byte(stderr.String())); !errors.Is(err, ErrDaemonNotRunning) {
+		t.Errorf("commandErrorWithStderr() = %v, want ErrDaemonNotRunning", err)
+	}
+
+	cmd = exec.Command("sh", "-c", "echo 'No such container: web' >&2; exit 1")
+	_, err = cmd.Output()
+	if err = commandError(cmd, err); environmentDown(err) {
+		t.Errorf("commandError() = %v, want a command-level failure", err)
+	}
+}
+
+func TestDockerCommandMaxConcurrency(t *testing.T) {
+	d := NewDockerMonitor([]string{"dev"})
+	d.UpdateEnvironment("dev", func(e *DockerEnvironment) {
+		e.MaxConcurrency = 1
+	})
+	ctx := context.Background()
+	_, release := d.DockerCommand(ctx, "dev", "version")
+
+	started := make(chan func())
+	go func() {
+		_, second := d.DockerCommand(ctx, "dev", "info")
+		started <- second
+	}()
+	select {
+	case <-started:
+		t.Fatal("second command got a slot while the first held the only one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Streams never wait for a slot, bounded events do.
+	_, stream := d.DockerCommand(ctx, "dev", "events")
+	stream()
+	bounded, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, done := d.DockerCommand(bounded, "dev", "events", "--since", "1", "--until", "2")
+	if bounded.Err() == nil {
+		t.Error("docker events --until got a slot while the first command held the only one")
+	}
+	done()
+
+	release()
+	release()
+	select {
+	case second := <-started:
+		second()
+	case <-time.After(time.Second):
+		t.Fatal("second command still waits after the first was released")
+	}
+	_, third := d.DockerCommand(ctx, "dev", "version")
+	third()
+}
+
+// fakeDocker puts a docker executable running the shell script body first
+// on PATH for the rest of the test.
+func fakeDocker(t *testing.T, body string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestStatsActionsShareWorkflow(t *testing.T) {
+	fakeDocker(t, "echo '{\"ID\":\"aaaaaaaaaaaa\",\"CPUPerc\":\"10.00%\",\"MemUsage\":\"100MiB / 1GiB\",\"MemPerc\":\"10.00%\",\"NetIO\":\"1kB / 2kB\",\"BlockIO\":\"0B / 0B\",\"PIDs\":\"3\"}'\n")
+
+	d := NewDockerMonitor([]string{"dev"})
+	d.StatsWindow = 5
+	d.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	d.UpdateEnvironment("dev", func(e *DockerEnvironment) {
+		e.ContainersInfo = []ContainerInfo{{ID: "aaaaaaaaaaaa", Names: "web", State: "running"}}
+	})
+	w := &Workflow{
+		Name:    "dev",
+		Actions: []Action{d.CallContainerStats(), d.CallContainerNetworkIO(time.Millisecond)},
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	for run := 0; run < 2; run++ {
+		if err := w.ExecuteActions(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dockerEnv, _ := d.Environment("dev")
+	stats := dockerEnv.ContainersInfo[0].Stats
+	if stats == nil || stats.Samples != 2 || stats.CPUPercentAvg != 10 {
+		t.Fatalf("Stats = %+v, want container-stats' average over 2 samples to survive container-network-io", stats)
+	}
+}
+
+// containerLine is `docker container ls` output for a running web container.
+const containerLine = `{"ID":"aaaaaaaaaaaa","Image":"nginx","Names":"web","State":"running","Status":"Up 2 hours"}`
+
+func TestCgroupStatsAcrossRuns(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("cgroup stats are only read on Linux")
+	}
+	fullID := "aaaaaaaaaaaa" + strings.Repeat("b", 52)
+	fakeDocker(t, fmt.Sprintf("case \"$1\" in\ncontainer) echo '%s' ;;\ninspect) echo '%s %s 4242' ;;\nesac\n", containerLine, fullID, fullID))
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+
+	root := t.TempDir()
+	cgroupDir := filepath.Join(root, "sys", "system.slice", "docker-"+fullID+".scope")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "sys", "cgroup.controllers"), "cpu memory pids\n")
+	write(filepath.Join(root, "proc", "4242", "cgroup"), "0::/system.slice/docker-"+fullID+".scope\n")
+	write(filepath.Join(cgroupDir, "memory.current"), "1048576\n")
+	write(filepath.Join(cgroupDir, "memory.max"), "2097152\n")
+	write(filepath.Join(cgroupDir, "pids.current"), "3\n")
+	write(filepath.Join(cgroupDir, "cpu.stat"), "usage_usec 1000\n")
+	oldCgroupRoot, oldProcRoot := cgroupRoot, procRoot
+	cgroupRoot, procRoot = filepath.Join(root, "sys"), filepath.Join(root, "proc")
+	t.Cleanup(func() { cgroupRoot, procRoot = oldCgroupRoot, oldProcRoot })
+
+	d := NewDockerMonitor([]string{"dev"})
+	d.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	w := &Workflow{
+		Name:    "dev",
+		Actions: []Action{d.CallContainersStatus(), d.CallContainerCgroupStats()},
+		Logger:  d.Logger,
+	}
+	if err := w.ExecuteActions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	// Half a second of CPU time within the pause is well above 0%.
+	write(filepath.Join(cgroupDir, "cpu.stat"), "usage_usec 501000\n")
+	if err := w.ExecuteActions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerEnv, _ := d.Environment("dev")
+	if stats := dockerEnv.ContainersInfo[0].Stats; stats == nil || stats.CPUPercent <= 0 || stats.CPUUsageMicros != 501000 {
+		t.Fatalf("Stats = %+v, want a CPUPercent from the previous run's sample", stats)
+	}
+}
+
+func TestZombieProcessesGrowingAcrossRuns(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "listed")
+	fakeDocker(t, fmt.Sprintf(`case "$1" in
+container) echo '%s' ;;
+top)
+	if [ -f %[2]s ]; then printf 'PID STAT\n1 Ss\n10 Z\n11 Z\n'; else touch %[2]s; printf 'PID STAT\n1 Ss\n10 Z\n'; fi ;;
+esac
+`, containerLine, marker))
+
+	var logs strings.Builder
+	d := NewDockerMonitor([]string{"dev"})
+	d.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	w := &Workflow{
+		Name:    "dev",
+		Actions: []Action{d.CallContainersStatus(), d.CallZombieProcesses()},
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	for run := 0; run < 2; run++ {
+		if err := w.ExecuteActions(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dockerEnv, _ := d.Environment("dev")
+	if got := dockerEnv.ContainersInfo[0].Zombies; got != 2 {
+		t.Errorf("Zombies = %d, want 2", got)
+	}
+	if !strings.Contains(logs.String(), "zombie processes growing") || !strings.Contains(logs.String(), "before=1 now=2") {
+		t.Errorf("logs = %s, want a growing zombies warning", logs.String())
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	d := NewDockerMonitor([]string{"dev"})
+	d.CommandTimeout = time.Minute
+	d.CommandTimeouts = map[string]time.Duration{"logs": time.Hour}
+	for _, tt := range []struct {
+		args []string
+		want time.Duration
+	}{
+		{[]string{"ps"}, time.Minute},
+		{[]string{"events"}, 0},
+		{[]string{"events", "--since", "1", "--until", "2"}, time.Minute},
+		{[]string{"logs", "-f", "web"}, time.Hour},
+	} {
+		if got := d.commandTimeout(tt.args); got != tt.want {
+			t.Errorf("commandTimeout(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
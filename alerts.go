@@ -0,0 +1,316 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AlertConfig is the "alerts" section of the configuration file: rules
+// raising alerts for environments and the notifiers they are sent to.
+type AlertConfig struct {
+	Rules     []AlertRule      `json:"rules"`
+	Notifiers []NotifierConfig `json:"notifiers"`
+}
+
+// AlertRule raises an alert for every environment where Condition, a
+// -fail-on expression such as "unhealthy" or "stopped>5", holds.
+type AlertRule struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+	// Severity is passed on to notifiers, e.g. "critical"; "warning" when empty.
+	Severity string `json:"severity,omitempty"`
+	// Tags, if set, restrict the rule to environments with any of them.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// NotifierConfig describes where alerts are sent. Type is "slack" or
+// "webhook", which post to URL, or "email", which sends through SMTPAddr.
+type NotifierConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+	// SMTPAddr is host:port; Username and Password, if set, are used for
+	// PLAIN authentication.
+	SMTPAddr string   `json:"smtpAddr,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	// Tags, if set, route only alerts of environments with any of them here.
+	Tags []string `json:"tags,omitempty"`
+	// Digest also sends the notifier a digest of each environment's docker
+	// events since the previous run, see AlertEngine.SendDigests.
+	Digest bool `json:"digest,omitempty"`
+}
+
+// Alert is a rule that holds for an environment.
+type Alert struct {
+	Rule        string `json:"rule"`
+	Environment string `json:"environment"`
+	Severity    string `json:"severity"`
+	Condition   string `json:"condition"`
+	Value       int    `json:"value"`
+	// tags are the environment's, for routing.
+	tags []string
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("[%s] %s: %s (%s, value %d)", a.Severity, a.Environment, a.Rule, a.Condition, a.Value)
+}
+
+// Notifier delivers alerts.
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// AlertEngine evaluates alert rules against a monitor and routes the alerts
+// to notifiers by environment tag.
+type AlertEngine struct {
+	rules  []alertRule
+	routes []alertRoute
+	// firing are the rule and environment pairs alerted on by the last RunWithAlerts.
+	firing map[[2]string]bool
+	// digestSince is where each environment's next event digest starts.
+	digestSince map[string]time.Time
+}
+
+type alertRule struct {
+	AlertRule
+	condition FailCondition
+}
+
+type alertRoute struct {
+	name     string
+	tags     []string
+	digest   bool
+	notifier Notifier
+}
+
+// NewAlertEngine builds the engine and notifiers described by cfg.
+func NewAlertEngine(cfg AlertConfig) (*AlertEngine, error) {
+	engine := &AlertEngine{firing: make(map[[2]string]bool), digestSince: make(map[string]time.Time)}
+	for _, rule := range cfg.Rules {
+		if rule.Name == "" {
+			return nil, errors.New("alert rule without a name")
+		}
+		condition, err := ParseFailCondition(rule.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %s: %w", rule.Name, err)
+		}
+		if rule.Severity == "" {
+			rule.Severity = "warning"
+		}
+		engine.rules = append(engine.rules, alertRule{AlertRule: rule, condition: condition})
+	}
+	for _, n := range cfg.Notifiers {
+		notifier, err := newNotifier(n)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %s: %w", n.Name, err)
+		}
+		if _, ok := notifier.(DigestNotifier); n.Digest && !ok {
+			return nil, fmt.Errorf("notifier %s: %s notifiers can't send digests", n.Name, n.Type)
+		}
+		engine.routes = append(engine.routes, alertRoute{name: n.Name, tags: n.Tags, digest: n.Digest, notifier: notifier})
+	}
+	return engine, nil
+}
+
+func newNotifier(cfg NotifierConfig) (Notifier, error) {
+	switch cfg.Type {
+	case "slack", "webhook":
+		if cfg.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		return &WebhookNotifier{URL: cfg.URL, Slack: cfg.Type == "slack"}, nil
+	case "email":
+		if cfg.SMTPAddr == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, errors.New("smtpAddr, from and to are required")
+		}
+		return &EmailNotifier{Addr: cfg.SMTPAddr, Username: cfg.Username, Password: cfg.Password, From: cfg.From, To: cfg.To}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q, valid types are email, slack, webhook", cfg.Type)
+}
+
+// Evaluate returns the alerts for d's current data, in environment and
+// rule order.
+func (a *AlertEngine) Evaluate(d *DockerMonitor) []Alert {
+	var alerts []Alert
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		for _, rule := range a.rules {
+			if len(rule.Tags) > 0 && !sharesTag(rule.Tags, dockerEnv.Tags) {
+				continue
+			}
+			if value, holds := rule.condition.holds(dockerEnv); holds {
+				alerts = append(alerts, Alert{Rule: rule.Name, Environment: dockerEnv.Environment, Severity: rule.Severity,
+					Condition: rule.Condition, Value: value, tags: dockerEnv.Tags})
+			}
+		}
+	}
+	return alerts
+}
+
+// Dispatch sends each notifier the alerts routed to it. A failing notifier
+// doesn't keep the others from being notified.
+func (a *AlertEngine) Dispatch(ctx context.Context, alerts []Alert) error {
+	var errs []error
+	for _, route := range a.routes {
+		var routed []Alert
+		for _, alert := range alerts {
+			if len(route.tags) == 0 || sharesTag(route.tags, alert.tags) {
+				routed = append(routed, alert)
+			}
+		}
+		if len(routed) == 0 {
+			continue
+		}
+		if err := route.notifier.Notify(ctx, routed); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", route.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunWithAlerts executes the workflows, computes the environments' status
+// with rules, and evaluates the alert rules, dispatching the alerts that
+// weren't already firing on the engine's previous run so a daemon running
+// it periodically doesn't repeat itself. It returns every alert that holds.
+func RunWithAlerts(ctx context.Context, d *DockerMonitor, workflows []*Workflow, rules StatusRules, engine *AlertEngine) ([]Alert, error) {
+	var errs []error
+	for _, w := range workflows {
+		if err := w.ExecuteActions(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("workflow %s: %w", w.Name, err))
+		}
+	}
+	d.ComputeStatus(rules)
+
+	alerts := engine.Evaluate(d)
+	firing := make(map[[2]string]bool)
+	var fresh []Alert
+	for _, alert := range alerts {
+		key := [2]string{alert.Rule, alert.Environment}
+		firing[key] = true
+		if !engine.firing[key] {
+			fresh = append(fresh, alert)
+		}
+	}
+	if err := engine.Dispatch(ctx, fresh); err != nil {
+		errs = append(errs, err)
+	} else {
+		// Alerts that couldn't be delivered are retried on the next run.
+		engine.firing = firing
+	}
+	return alerts, errors.Join(errs...)
+}
+
+func sharesTag(a, b []string) bool {
+	for _, tag := range a {
+		if slices.Contains(b, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyTimeout bounds a single notification.
+const notifyTimeout = 10 * time.Second
+
+// WebhookNotifier posts alerts as JSON to URL: {"alerts": [...]}, or a
+// Slack incoming webhook message when Slack is set.
+type WebhookNotifier struct {
+	URL   string
+	Slack bool
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	var payload any = struct {
+		Alerts []Alert `json:"alerts"`
+	}{alerts}
+	if n.Slack {
+		payload = slackMessage{alertText(alerts)}
+	}
+	return n.post(ctx, payload)
+}
+
+// NotifyDigest posts {"digests": [...]}, or a Slack message.
+func (n *WebhookNotifier) NotifyDigest(ctx context.Context, digests []EventDigest) error {
+	var payload any = struct {
+		Digests []EventDigest `json:"digests"`
+	}{digests}
+	if n.Slack {
+		payload = slackMessage{digestText(digests)}
+	}
+	return n.post(ctx, payload)
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func (n *WebhookNotifier) post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", n.URL, resp.Status)
+	}
+	return nil
+}
+
+// EmailNotifier sends alerts as a plain text email through the SMTP server
+// at Addr.
+type EmailNotifier struct {
+	Addr               string
+	Username, Password string
+	From               string
+	To                 []string
+}
+
+// Notify ignores ctx, which net/smtp doesn't support.
+func (n *EmailNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	return n.send(fmt.Sprintf("dockermonitor: %d alert(s)", len(alerts)), alertText(alerts))
+}
+
+// NotifyDigest ignores ctx, like Notify.
+func (n *EmailNotifier) NotifyDigest(ctx context.Context, digests []EventDigest) error {
+	return n.send(fmt.Sprintf("dockermonitor: events on %d environment(s)", len(digests)), digestText(digests))
+}
+
+func (n *EmailNotifier) send(subject, text string) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		host, _, _ := strings.Cut(n.Addr, ":")
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		n.From, strings.Join(n.To, ", "), subject, strings.ReplaceAll(text, "\n", "\r\n"))
+	return smtp.SendMail(n.Addr, auth, n.From, n.To, []byte(msg))
+}
+
+func alertText(alerts []Alert) string {
+	lines := make([]string, len(alerts))
+	for i, alert := range alerts {
+		lines[i] = alert.String()
+	}
+	return strings.Join(lines, "\n")
+}
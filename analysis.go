@@ -0,0 +1,177 @@
+package dockermonitor
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ContainersPerImage counts running containers grouped by their Image field,
+// e.g. to see how many replicas of a service are up. It only uses data that
+// CheckContainersStatus already collected.
+func (e *DockerEnvironment) ContainersPerImage() map[string]int {
+	perImage := make(map[string]int)
+	for _, cont := range e.ContainersInfo {
+		if cont.State == "running" {
+			perImage[cont.Image] += 1
+		}
+	}
+	return perImage
+}
+
+// BaseOSCounts counts local images by the BaseOS CheckImageBaseOS found,
+// e.g. for an inventory of the distributions in use.
+func (e *DockerEnvironment) BaseOSCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, img := range e.ImagesInfo {
+		if img.BaseOS != "" {
+			counts[img.BaseOS] += 1
+		}
+	}
+	return counts
+}
+
+// TagsByImageID groups the "repository:tag" references of local images by
+// image ID, since `docker images` lists a row per tag. Untagged images map
+// to an empty list.
+func (e *DockerEnvironment) TagsByImageID() map[string][]string {
+	tags := make(map[string][]string)
+	for _, img := range e.ImagesInfo {
+		if _, found := tags[img.ID]; !found {
+			tags[img.ID] = []string{}
+		}
+		if img.Repository == "<none>" || img.Tag == "<none>" {
+			continue
+		}
+		if ref := img.Repository + ":" + img.Tag; !slices.Contains(tags[img.ID], ref) {
+			tags[img.ID] = append(tags[img.ID], ref)
+		}
+	}
+	for _, refs := range tags {
+		slices.Sort(refs)
+	}
+	return tags
+}
+
+// imageMatchesReference reports whether img is the image a container refers to
+// through ref, which may be a repository[:tag], a repository@digest or a
+// (possibly shortened) image ID.
+func imageMatchesReference(ref string, img ImageInfo) bool {
+	id := strings.TrimPrefix(ref, "sha256:")
+	if img.ID != "" && len(id) >= 12 && (strings.HasPrefix(id, img.ID) || strings.HasPrefix(img.ID, id)) {
+		return true
+	}
+
+	if repository, digest, found := strings.Cut(ref, "@"); found {
+		return repository == img.Repository && digest == img.Digest
+	}
+
+	repository, tag := ref, "latest"
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repository, tag = ref[:i], ref[i+1:]
+	}
+	return repository == img.Repository && tag == img.Tag
+}
+
+// imageInUse counts the containers, running or stopped, created from img.
+func (e *DockerEnvironment) imageInUse(img ImageInfo) int {
+	count := 0
+	for _, cont := range e.ContainersInfo {
+		if imageMatchesReference(cont.Image, img) {
+			count += 1
+		}
+	}
+	return count
+}
+
+// runningFrom reports whether a running container was created from img.
+func (e *DockerEnvironment) runningFrom(img ImageInfo) bool {
+	for _, cont := range e.ContainersInfo {
+		if cont.State == "running" && imageMatchesReference(cont.Image, img) {
+			return true
+		}
+	}
+	return false
+}
+
+type CheckImageUsage struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckImageUsage) Name() string        { return "image-usage" }
+func (c CheckImageUsage) DependsOn() []string { return []string{"containers-status", "local-images"} }
+
+// Execute derives per-image container counts from the collected containers,
+// since `docker images` usually reports N/A, and records images no container
+// uses as UnusedImages.
+func (c CheckImageUsage) Execute(ctx context.Context, env string) error {
+	unused := 0
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.UnusedImages = nil
+		for i, img := range e.ImagesInfo {
+			count := e.imageInUse(img)
+			e.ImagesInfo[i].Containers = strconv.Itoa(count)
+			if count == 0 {
+				e.UnusedImages = append(e.UnusedImages, img)
+			}
+		}
+		unused = len(e.UnusedImages)
+	})
+	c.dockerMonitor.logger().Info("image usage", "environment", env, "unused", unused)
+	return nil
+}
+
+// isImageID reports whether ref is a bare image ID rather than a repository
+// reference, which is what `docker ps` shows once the container's tag is gone.
+func isImageID(ref string) bool {
+	id := strings.TrimPrefix(ref, "sha256:")
+	if len(id) < 12 {
+		return false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+type CheckOrphanedContainers struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckOrphanedContainers) Name() string { return "orphaned-containers" }
+func (c CheckOrphanedContainers) DependsOn() []string {
+	return []string{"containers-status", "local-images"}
+}
+
+// Execute flags containers, running or stopped, whose image can no longer be
+// found locally by repository:tag. Such containers can't be recreated as-is.
+func (c CheckOrphanedContainers) Execute(ctx context.Context, env string) error {
+	orphaned := 0
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.OrphanedContainers = nil
+		for _, cont := range e.ContainersInfo {
+			if isImageID(cont.Image) || !slices.ContainsFunc(e.ImagesInfo, func(img ImageInfo) bool {
+				return imageMatchesReference(cont.Image, img)
+			}) {
+				e.OrphanedContainers = append(e.OrphanedContainers, cont.clone())
+			}
+		}
+		orphaned = len(e.OrphanedContainers)
+	})
+	c.dockerMonitor.logger().Info("orphaned containers", "environment", env, "orphaned", orphaned)
+	return nil
+}
+
+// UnhealthyContainers returns the containers whose health check currently fails.
+func (e *DockerEnvironment) UnhealthyContainers() []ContainerInfo {
+	var unhealthy []ContainerInfo
+	for _, cont := range e.ContainersInfo {
+		if strings.Contains(cont.Status, "(unhealthy)") {
+			unhealthy = append(unhealthy, cont)
+		}
+	}
+	return unhealthy
+}
@@ -0,0 +1,64 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+)
+
+// ArchMismatch is a local image built for a different CPU architecture than
+// the daemon runs on, so containers from it run emulated or not at all.
+type ArchMismatch struct {
+	Image    string `json:"image"`
+	ID       string `json:"id"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+type CheckCPUArchitecture struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckCPUArchitecture) Name() string        { return "cpu-architecture" }
+func (c CheckCPUArchitecture) DependsOn() []string { return []string{"local-images"} }
+
+func (c CheckCPUArchitecture) Execute(ctx context.Context, env string) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "version", "--format", "{{.Server.Arch}}")
+	if err != nil {
+		return err
+	}
+	daemonArch := strings.TrimSpace(string(out))
+
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	var ids []string
+	for _, img := range dockerEnv.ImagesInfo {
+		ids = append(ids, img.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.Architecture}}")
+	if err != nil {
+		return err
+	}
+
+	var mismatches []ArchMismatch
+	for _, img := range dockerEnv.ImagesInfo {
+		arch, found := inspected[img.ID]
+		if !found || arch == "" || arch == daemonArch {
+			continue
+		}
+		mismatches = append(mismatches, ArchMismatch{
+			Image:    img.Repository + ":" + img.Tag,
+			ID:       img.ID,
+			Expected: daemonArch,
+			Actual:   arch,
+		})
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.DaemonArchitecture = daemonArch
+		e.ArchMismatches = mismatches
+	})
+	c.dockerMonitor.logger().Info("image architectures", "environment", env, "daemon", daemonArch, "mismatches", len(mismatches))
+	return nil
+}
@@ -0,0 +1,182 @@
+package dockermonitor
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+)
+
+// BaseOSUnknown is the BaseOS of images CheckImageBaseOS couldn't identify.
+const BaseOSUnknown = "unknown"
+
+// baseOSPrefixes map image name prefixes, such as "alpine" or "ubi9", to a
+// base OS family.
+var baseOSPrefixes = []struct{ prefix, family string }{
+	{"alpine", "alpine"},
+	{"debian", "debian"},
+	{"ubuntu", "ubuntu"},
+	{"centos", "centos"},
+	{"fedora", "fedora"},
+	{"ubi", "rhel"},
+	{"amazonlinux", "amazonlinux"},
+	{"busybox", "busybox"},
+}
+
+type CheckImageBaseOS struct {
+	dockerMonitor *DockerMonitor
+	// ReadOSRelease also reads /etc/os-release from each image, which finds
+	// the base of images like "node" or "python" that metadata doesn't name.
+	// It creates, but never starts, a throwaway container per image.
+	ReadOSRelease bool
+}
+
+func (c CheckImageBaseOS) Name() string        { return "image-base-os" }
+func (c CheckImageBaseOS) DependsOn() []string { return []string{"local-images"} }
+
+// Execute sets Platform and BaseOS on every local image. Detection is best
+// effort: /etc/os-release when enabled, then the OCI base.name and ref.name
+// labels, then the image's own name; anything else is BaseOSUnknown.
+func (c CheckImageBaseOS) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, img := range dockerEnv.ImagesInfo {
+		ids = append(ids, img.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.Os}}/{{.Architecture}} {{json .Config.Labels}}")
+	if err != nil {
+		return err
+	}
+
+	platforms := make(map[string]string)
+	baseOSes := make(map[string]string)
+	for _, img := range dockerEnv.ImagesInfo {
+		value, found := inspected[img.ID]
+		if !found {
+			continue
+		}
+		platform, rawLabels, _ := strings.Cut(value, " ")
+		var labels map[string]string
+		if err := unmarshalLine(rawLabels, "image labels", &labels); err != nil {
+			return err
+		}
+		platforms[img.ID] = platform
+
+		baseOS := ""
+		if c.ReadOSRelease {
+			baseOS, err = c.readOSRelease(ctx, env, img.ID)
+			if environmentDown(err) {
+				return err
+			}
+			if err != nil {
+				c.dockerMonitor.logger().Warn("reading os-release", "environment", env, "image", img.Repository+":"+img.Tag, "error", err)
+			}
+		}
+		if baseOS == "" {
+			baseOS = baseOSFromMetadata(platform, labels, img.Repository)
+		}
+		baseOSes[img.ID] = baseOS
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ImagesInfo {
+			if platform, ok := platforms[e.ImagesInfo[i].ID]; ok {
+				e.ImagesInfo[i].Platform = platform
+				e.ImagesInfo[i].BaseOS = baseOSes[e.ImagesInfo[i].ID]
+			}
+		}
+	})
+	updated, _ := c.dockerMonitor.Environment(env)
+	c.dockerMonitor.logger().Info("image base OS", "environment", env, "counts", updated.BaseOSCounts())
+	return nil
+}
+
+// readOSRelease copies /etc/os-release out of a created container and
+// returns its base OS family, or "" when the image has none.
+func (c CheckImageBaseOS) readOSRelease(ctx context.Context, env, image string) (string, error) {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	// The command is never run; it only lets images without CMD be created.
+	cmd, release := c.dockerMonitor.DockerCommand(ctx, env, "create", "--network", "none", image, "true")
+	out, err := cmd.Output()
+	release()
+	if err != nil {
+		return "", commandError(cmd, err)
+	}
+	id := strings.TrimSpace(string(out))
+	defer func() {
+		rm, release := c.dockerMonitor.DockerCommand(context.WithoutCancel(ctx), env, "rm", id)
+		defer release()
+		if err := rm.Run(); err != nil {
+			c.dockerMonitor.logger().Warn("removing os-release container", "environment", env, "container", id, "error", commandError(rm, err))
+		}
+	}()
+
+	// -L follows the usual symlink to /usr/lib/os-release. The file comes back as a tar stream.
+	cmd, release = c.dockerMonitor.DockerCommand(ctx, env, "cp", "-L", id+":/etc/os-release", "-")
+	out, err = cmd.Output()
+	release()
+	if err != nil {
+		return "", commandError(cmd, err)
+	}
+	archive := tar.NewReader(bytes.NewReader(out))
+	if _, err := archive.Next(); err != nil {
+		return "", err
+	}
+	return baseOSFromOSRelease(archive), nil
+}
+
+// baseOSFromOSRelease reads the ID of an os-release file. Distroless images
+// carry Debian's ID but a "Distroless" PRETTY_NAME.
+func baseOSFromOSRelease(r io.Reader) string {
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if found {
+			fields[key] = strings.Trim(value, `"'`)
+		}
+	}
+	if strings.Contains(strings.ToLower(fields["PRETTY_NAME"]), "distroless") {
+		return "distroless"
+	}
+	return strings.ToLower(fields["ID"])
+}
+
+// baseOSFromMetadata guesses the base OS family from an image's labels and
+// repository, falling back to its OS for Windows images.
+func baseOSFromMetadata(platform string, labels map[string]string, repository string) string {
+	for _, name := range []string{
+		labels["org.opencontainers.image.base.name"],
+		labels["org.opencontainers.image.ref.name"],
+		repository,
+	} {
+		if family := baseOSFromName(name); family != "" {
+			return family
+		}
+	}
+	if strings.HasPrefix(platform, "windows/") {
+		return "windows"
+	}
+	return BaseOSUnknown
+}
+
+// baseOSFromName matches image references like "docker.io/library/alpine:3.19"
+// or "gcr.io/distroless/static".
+func baseOSFromName(name string) string {
+	name = strings.ToLower(name)
+	if strings.Contains(name, "distroless") {
+		return "distroless"
+	}
+	name, _, _ = strings.Cut(name[strings.LastIndex(name, "/")+1:], ":")
+	for _, p := range baseOSPrefixes {
+		if strings.HasPrefix(name, p.prefix) {
+			return p.family
+		}
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package dockermonitor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// syntheticContainers returns n lines of `docker ps --format '{{json .}}'`.
+func syntheticContainers(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `{"Command":"\"docker-entrypoint.s…\"","CreatedAt":"2024-01-01 10:00:00 +0000 UTC","ID":"%012x","Image":"registry.example.com/team/app-%d:1.%d","Labels":"com.docker.compose.project=app,com.docker.compose.service=svc-%d","LocalVolumes":"1","Mounts":"data-%d","Names":"app-%d","Networks":"bridge","Ports":"0.0.0.0:%d->80/tcp","RunningFor":"2 hours ago","Size":"0B","State":"running","Status":"Up 2 hours"}`+"\n",
+			i, i%50, i%7, i, i, i, 10000+i)
+	}
+	return b.String()
+}
+
+// syntheticImages returns n lines of `docker images --format '{{json .}}'`.
+func syntheticImages(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `{"Containers":"N/A","CreatedAt":"2024-01-01 00:00:00 +0000 UTC","CreatedSince":"2 weeks ago","Digest":"<none>","ID":"%012x","Repository":"registry.example.com/team/app-%d","SharedSize":"N/A","Size":"187MB","Tag":"1.%d","UniqueSize":"N/A","VirtualSize":"187MB"}`+"\n",
+			i, i, i%7)
+	}
+	return b.String()
+}
+
+func BenchmarkParseContainers(b *testing.B) {
+	out := syntheticContainers(1000)
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseContainers(out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseImages(b *testing.B) {
+	out := syntheticImages(1000)
+	b.SetBytes(int64(len(out)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseImages(out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// bigMonitor has five environments of 1,000 containers and images each.
+func bigMonitor(tb testing.TB) *DockerMonitor {
+	containers, err := parseContainers(syntheticContainers(1000))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	images, err := parseImages(syntheticImages(1000))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var names []string
+	for i := 0; i < 5; i++ {
+		names = append(names, fmt.Sprintf("env-%d", i))
+	}
+	d := NewDockerMonitor(names)
+	for _, name := range names {
+		d.UpdateEnvironment(name, func(e *DockerEnvironment) {
+			e.ContainersInfo = containers
+			e.ImagesInfo = images
+			e.RunningContainers = len(containers)
+			e.TotalLocalDockerImages = len(images)
+		})
+	}
+	return d
+}
+
+func BenchmarkJSONFormatter(b *testing.B) {
+	d := bigMonitor(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := (JSONFormatter{Compact: true}).Format(io.Discard, d); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestParseAllocs guards the parsers against regressions the benchmarks
+// would only show when someone runs them: allocations must stay linear in
+// the number of lines, at a small constant per line. Containers take about
+// 18 allocations a line and images 11; the ceiling leaves room for small
+// parser changes while still catching a per-field copy.
+func TestParseAllocs(t *testing.T) {
+	if raceEnabled || testing.Short() {
+		t.Skip("allocation counts are only meaningful in a full run without the race detector")
+	}
+	const lines, maxPerLine = 1000, 30
+	containers, images := syntheticContainers(lines), syntheticImages(lines)
+	for name, parse := range map[string]func(){
+		"containers": func() { parseContainers(containers) },
+		"images":     func() { parseImages(images) },
+	} {
+		if allocs := testing.AllocsPerRun(5, parse); allocs > lines*maxPerLine {
+			t.Errorf("parsing %d %s allocates %.0f times, want at most %d", lines, name, allocs, lines*maxPerLine)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package dockermonitor
+
+import (
+	"bufio"
+	"context"
+	"slices"
+	"strings"
+)
+
+// BuilderNode is one node of a buildx builder.
+type BuilderNode struct {
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BuilderInfo holds a configured buildx builder.
+type BuilderInfo struct {
+	Name   string        `json:"name"`
+	Driver string        `json:"driver"`
+	Nodes  []BuilderNode `json:"nodes"`
+}
+
+// unhealthy reports whether any node of the builder is stopped or errored.
+func (b BuilderInfo) unhealthy() bool {
+	return slices.ContainsFunc(b.Nodes, func(n BuilderNode) bool {
+		return n.Error != "" || n.Status == "stopped" || n.Status == "inactive" || n.Status == "error"
+	})
+}
+
+type CheckBuilders struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckBuilders) Name() string { return "buildx-builders" }
+
+func (c CheckBuilders) Execute(ctx context.Context, env string) error {
+	if err := c.dockerMonitor.requireCapability(env, CapabilityBuildx); err != nil {
+		return err
+	}
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	cmd, release := c.dockerMonitor.DockerCommand(ctx, env, "buildx", "ls", "--format", "{{json .}}")
+	out, err := cmd.Output()
+	release()
+	var builders []BuilderInfo
+	if err == nil {
+		builders, err = parseBuildersJSON(string(out))
+	}
+	if err != nil {
+		// buildx releases before 0.13 have no --format and only print a table.
+		cmd, release = c.dockerMonitor.DockerCommand(ctx, env, "buildx", "ls")
+		out, err = cmd.Output()
+		release()
+		if err != nil {
+			return commandError(cmd, err)
+		}
+		builders = parseBuildersTable(string(out))
+	}
+
+	var unhealthy []string
+	for _, builder := range builders {
+		if builder.unhealthy() {
+			unhealthy = append(unhealthy, builder.Name)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.Builders = builders
+		e.UnhealthyBuilders = unhealthy
+	})
+	c.dockerMonitor.logger().Info("buildx builders", "environment", env, "builders", len(builders), "unhealthy", len(unhealthy))
+	return nil
+}
+
+func parseBuildersJSON(out string) ([]BuilderInfo, error) {
+	var builders []BuilderInfo
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var raw struct {
+			Name   string
+			Driver string
+			Nodes  []struct {
+				Name     string
+				Endpoint string
+				Status   string
+				Err      string
+			}
+		}
+		if err := unmarshalLine(line, "BuilderInfo", &raw); err != nil {
+			return nil, err
+		}
+		builder := BuilderInfo{Name: raw.Name, Driver: raw.Driver}
+		for _, n := range raw.Nodes {
+			builder.Nodes = append(builder.Nodes, BuilderNode{Name: n.Name, Endpoint: n.Endpoint, Status: n.Status, Error: n.Err})
+		}
+		builders = append(builders, builder)
+	}
+	return builders, nil
+}
+
+// parseBuildersTable reads the legacy `docker buildx ls` table, where builders
+// start at the first column and their nodes are indented below them:
+//
+//	NAME/NODE     DRIVER/ENDPOINT  STATUS   BUILDKIT  PLATFORMS
+//	default *     docker
+//	  default     default          running  v0.11.6   linux/amd64
+func parseBuildersTable(out string) []BuilderInfo {
+	var builders []BuilderInfo
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "NAME/NODE" {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			builder := BuilderInfo{Name: fields[0]}
+			if len(fields) > 1 && fields[1] != "*" {
+				builder.Driver = fields[1]
+			} else if len(fields) > 2 {
+				builder.Driver = fields[2]
+			}
+			builders = append(builders, builder)
+			continue
+		}
+		if len(builders) == 0 {
+			continue
+		}
+		node := BuilderNode{Name: strings.TrimPrefix(fields[0], "\\_")}
+		if len(fields) > 1 {
+			node.Endpoint = fields[1]
+		}
+		if len(fields) > 2 {
+			node.Status = fields[2]
+		}
+		last := &builders[len(builders)-1]
+		last.Nodes = append(last.Nodes, node)
+	}
+	return builders
+}
@@ -0,0 +1,129 @@
+package dockermonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Capability names an optional docker feature that not every daemon or CLI
+// installation provides.
+type Capability string
+
+const (
+	CapabilityStats  Capability = "stats"
+	CapabilityBuildx Capability = "buildx"
+	CapabilityScout  Capability = "scout"
+	CapabilitySwarm  Capability = "swarm"
+)
+
+// ErrUnsupported is wrapped by errors from actions that the environment can't run.
+// Workflows record such actions as skipped instead of aborting.
+var ErrUnsupported = errors.New("not supported by this docker environment")
+
+func unsupported(reason string) error {
+	return fmt.Errorf("%w: %s", ErrUnsupported, reason)
+}
+
+// ActionStatus is the outcome of running a single action.
+type ActionStatus string
+
+const (
+	ActionSucceeded ActionStatus = "succeeded"
+	ActionFailed    ActionStatus = "failed"
+	ActionSkipped   ActionStatus = "skipped"
+)
+
+// ActionResult records what happened to one action in a workflow run.
+type ActionResult struct {
+	Action      string       `json:"action"`
+	Environment string       `json:"environment"`
+	Status      ActionStatus `json:"status"`
+	Reason      string       `json:"reason,omitempty"`
+	Err         error        `json:"-"`
+}
+
+func newActionResult(action, env string, err error) ActionResult {
+	result := ActionResult{Action: action, Environment: env, Status: ActionSucceeded, Err: err}
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrUnsupported):
+		result.Status = ActionSkipped
+		result.Reason = err.Error()
+	default:
+		result.Status = ActionFailed
+		result.Reason = err.Error()
+	}
+	return result
+}
+
+type CheckCapabilities struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckCapabilities) Name() string { return "capabilities" }
+
+func (c CheckCapabilities) Execute(ctx context.Context, env string) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "version", "--format", "{{.Server.APIVersion}}")
+	if err != nil {
+		return err
+	}
+	apiVersion := strings.TrimSpace(string(out))
+
+	succeeds := func(args ...string) bool {
+		cmd, release := c.dockerMonitor.DockerCommand(ctx, env, args...)
+		defer release()
+		return cmd.Run() == nil
+	}
+	capabilities := map[Capability]bool{
+		CapabilityStats:  true,
+		CapabilityBuildx: succeeds("buildx", "version"),
+		CapabilityScout:  succeeds("scout", "version"),
+	}
+
+	// Rootless daemons on cgroup v1 can't report resource usage, and swarm
+	// commands only work once the daemon joined a swarm.
+	out, err = c.dockerMonitor.DockerOutput(ctx, env, "info", "--format", "{{.CgroupVersion}} {{.Swarm.LocalNodeState}} {{json .SecurityOptions}}")
+	if err == nil {
+		fields := strings.SplitN(strings.TrimSpace(string(out)), " ", 3)
+		if len(fields) == 3 {
+			rootless := strings.Contains(fields[2], "name=rootless")
+			capabilities[CapabilityStats] = !(rootless && fields[0] == "1")
+			capabilities[CapabilitySwarm] = fields[1] == "active"
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.APIVersion = apiVersion
+		e.Capabilities = capabilities
+	})
+
+	var missing []string
+	for capability, supported := range capabilities {
+		if !supported {
+			missing = append(missing, string(capability))
+		}
+	}
+	sort.Strings(missing)
+	c.dockerMonitor.logger().Info("capabilities", "environment", env, "apiVersion", apiVersion, "unsupported", missing)
+	return nil
+}
+
+// requireCapability returns an ErrUnsupported error when capability detection
+// ran for env and found the capability missing. Environments that were never
+// probed are assumed to support everything.
+func (d *DockerMonitor) requireCapability(env string, capability Capability) error {
+	dockerEnv, found := d.Environment(env)
+	if !found || dockerEnv.Capabilities == nil {
+		return nil
+	}
+	if supported, detected := dockerEnv.Capabilities[capability]; detected && !supported {
+		return unsupported(fmt.Sprintf("%s is not available in %s", capability, env))
+	}
+	return nil
+}
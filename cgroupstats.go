@@ -0,0 +1,258 @@
+package dockermonitor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Where CheckContainerCgroupStats finds the cgroup v2 hierarchy and the
+// processes of local containers.
+var (
+	cgroupRoot = "/sys/fs/cgroup"
+	procRoot   = "/proc"
+)
+
+// CheckContainerCgroupStats reads container resource usage straight from the
+// cgroup v2 files of local containers instead of running `docker stats`,
+// which takes seconds per sample, so it suits high-frequency polling. CPU
+// percentages are computed against the previous run's sample, which the
+// action remembers like CheckContainerHealthHistory, and are 0 on the first
+// run. Remote environments, non-Linux hosts and cgroup v1 fall back to
+// CheckContainerStats.
+type CheckContainerCgroupStats struct {
+	dockerMonitor *DockerMonitor
+	history       *cgroupCPUHistory
+}
+
+type cgroupCPUSample struct {
+	at          time.Time
+	usageMicros int64
+}
+
+// cgroupCPUHistory holds the previous CPU reading of every container, keyed
+// by environment and container ID.
+type cgroupCPUHistory struct {
+	mu      sync.Mutex
+	samples map[[2]string]cgroupCPUSample
+}
+
+func (c CheckContainerCgroupStats) Name() string        { return "container-cgroup-stats" }
+func (c CheckContainerCgroupStats) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerCgroupStats) Execute(ctx context.Context, env string) error {
+	if runtime.GOOS != "linux" || !c.dockerMonitor.runsLocally(env) || !cgroupV2() {
+		c.dockerMonitor.logger().Info("cgroup v2 stats unavailable, using docker stats", "environment", env)
+		return c.dockerMonitor.dockerStats(ctx, env, c.Name())
+	}
+
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	ids := runningContainerIDs(dockerEnv)
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.Id}} {{.State.Pid}}")
+	if err != nil {
+		return err
+	}
+
+	stats := make(map[string]ContainerStats)
+	sampledAt := make(map[string]time.Time)
+	var firstErr error
+	for _, id := range ids {
+		fullID, pid, _ := strings.Cut(strings.TrimSpace(inspected[id]), " ")
+		if pid == "" || pid == "0" {
+			continue
+		}
+		at := time.Now()
+		s, err := readCgroupStats(fullID, pid)
+		if err != nil {
+			// The container may have stopped since it was listed.
+			c.dockerMonitor.logger().Warn("reading cgroup stats", "environment", env, "container", id, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		stats[id] = s
+		sampledAt[id] = at
+	}
+	// Typically /proc of the daemon's processes isn't readable, e.g. when
+	// the monitor runs in a container of its own.
+	if len(stats) == 0 && firstErr != nil {
+		c.dockerMonitor.logger().Warn("cgroup v2 stats unreadable, using docker stats", "environment", env, "error", firstErr)
+		return c.dockerMonitor.dockerStats(ctx, env, c.Name())
+	}
+
+	h := c.history
+	h.mu.Lock()
+	for key := range h.samples {
+		if _, found := stats[key[1]]; key[0] == env && !found {
+			delete(h.samples, key)
+		}
+	}
+	for id, s := range stats {
+		key := [2]string{env, id}
+		before, found := h.samples[key]
+		h.samples[key] = cgroupCPUSample{at: sampledAt[id], usageMicros: s.CPUUsageMicros}
+		// Usage restarts from zero when the container does.
+		if elapsed := sampledAt[id].Sub(before.at).Microseconds(); found && elapsed > 0 && s.CPUUsageMicros >= before.usageMicros {
+			s.CPUPercent = float64(s.CPUUsageMicros-before.usageMicros) / float64(elapsed) * 100
+			stats[id] = s
+		}
+	}
+	h.mu.Unlock()
+
+	c.dockerMonitor.storeStats(env, c.Name(), stats)
+	c.dockerMonitor.logger().Info("container cgroup stats", "environment", env, "sampled", len(stats))
+	return nil
+}
+
+// runsLocally reports whether env's daemon runs on this machine, so the
+// processes of its containers are visible in /proc.
+func (d *DockerMonitor) runsLocally(env string) bool {
+	dockerEnv, _ := d.Environment(env)
+	if dockerEnv.Host != "" {
+		return false
+	}
+	for _, arg := range d.GlobalArgs {
+		if arg == "-H" || arg == "-c" || strings.HasPrefix(arg, "--host") || strings.HasPrefix(arg, "--context") {
+			return false
+		}
+	}
+	host := os.Getenv("DOCKER_HOST")
+	return (host == "" || strings.HasPrefix(host, "unix://")) && os.Getenv("DOCKER_CONTEXT") == ""
+}
+
+// cgroupV2 reports whether the unified cgroup v2 hierarchy is mounted.
+func cgroupV2() bool {
+	_, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers"))
+	return err == nil
+}
+
+// readCgroupStats reads the usage of the container whose main process is
+// pid. The cgroup must name the container's full ID, which guards against
+// reading an unrelated process when the daemon runs in a VM.
+func readCgroupStats(fullID, pid string) (ContainerStats, error) {
+	membership, err := os.ReadFile(filepath.Join(procRoot, pid, "cgroup"))
+	if err != nil {
+		return ContainerStats{}, err
+	}
+	// cgroup v2 has a single "0::<path>" line.
+	_, path, found := strings.Cut(strings.TrimSpace(string(membership)), "0::")
+	if !found || !strings.Contains(path, fullID) {
+		return ContainerStats{}, fmt.Errorf("process %s isn't in the cgroup of container %s", pid, fullID)
+	}
+	dir := filepath.Join(cgroupRoot, path)
+
+	var s ContainerStats
+	if s.MemoryUsageBytes, err = readCgroupInt(dir, "memory.current"); err != nil {
+		return ContainerStats{}, err
+	}
+	s.MemoryLimitBytes, err = readCgroupInt(dir, "memory.max")
+	if errors.Is(err, errUnlimited) {
+		// Like docker stats, unlimited containers are measured against the host.
+		s.MemoryLimitBytes, err = hostMemory()
+	}
+	if err != nil {
+		return ContainerStats{}, err
+	}
+	if s.MemoryLimitBytes > 0 {
+		s.MemoryPercent = float64(s.MemoryUsageBytes) / float64(s.MemoryLimitBytes) * 100
+	}
+	pids, err := readCgroupInt(dir, "pids.current")
+	if err != nil {
+		return ContainerStats{}, err
+	}
+	s.PIDs = int(pids)
+
+	cpu, err := readKeyedFile(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return ContainerStats{}, err
+	}
+	s.CPUUsageMicros = cpu["usage_usec"]
+	// io.stat has a "<major>:<minor> rbytes=… wbytes=…" line per device.
+	if io, err := os.ReadFile(filepath.Join(dir, "io.stat")); err == nil {
+		for _, field := range strings.Fields(string(io)) {
+			key, value, _ := strings.Cut(field, "=")
+			n, _ := strconv.ParseInt(value, 10, 64)
+			switch key {
+			case "rbytes":
+				s.BlockReadBytes += n
+			case "wbytes":
+				s.BlockWriteBytes += n
+			}
+		}
+	}
+	// The process sees the container's network namespace.
+	s.NetRxBytes, s.NetTxBytes = readNetDev(filepath.Join(procRoot, pid, "net", "dev"))
+	return s, nil
+}
+
+// errUnlimited is returned by readCgroupInt for files set to "max".
+var errUnlimited = errors.New("unlimited")
+
+func readCgroupInt(dir, name string) (int64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, errUnlimited
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// readKeyedFile parses files of "key value" lines such as cpu.stat.
+func readKeyedFile(path string) (map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	values := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			values[fields[0]], _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readNetDev sums the received and transmitted bytes of every interface but
+// loopback in a /proc/<pid>/net/dev file; unreadable files count as 0.
+func readNetDev(path string) (rx, tx int64) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		name, counters, found := strings.Cut(line, ":")
+		fields := strings.Fields(counters)
+		if !found || strings.TrimSpace(name) == "lo" || len(fields) < 9 {
+			continue
+		}
+		received, _ := strconv.ParseInt(fields[0], 10, 64)
+		transmitted, _ := strconv.ParseInt(fields[8], 10, 64)
+		rx += received
+		tx += transmitted
+	}
+	return rx, tx
+}
+
+// hostMemory returns MemTotal from /proc/meminfo in bytes.
+func hostMemory() (int64, error) {
+	data, err := os.ReadFile(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+	return meminfoBytes(string(data), "MemTotal")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/adrien19/dockermonitor"
+)
+
+// runAlert runs the actions, evaluates the alert rules of the config file and
+// notifies about the alerts, once or every -interval until interrupted.
+func runAlert(args []string) int {
+	flags := flag.NewFlagSet("alert", flag.ExitOnError)
+	var collect collectFlags
+	collect.register(flags)
+	interval := flags.Duration("interval", 0, "run again at this interval, e.g. 5m, until interrupted; 0 runs once")
+	flags.Parse(args)
+
+	logger := collect.logger()
+	c, err := collect.setup(logger)
+	if err != nil {
+		logger.Error("setting up", "error", err)
+		return exitUsage
+	}
+	defer c.close()
+	if c.cfg.Alerts == nil {
+		logger.Error("setting up", "error", errors.New("the config has no alerts section"))
+		return exitUsage
+	}
+	engine, err := dockermonitor.NewAlertEngine(*c.cfg.Alerts)
+	if err != nil {
+		logger.Error("setting up", "error", err)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Resume state only applies to the first run.
+	resume := c.resumeState
+	for {
+		workflows := newWorkflows(c.monitor, c.cfg.EnvironmentNames(), c.actions, logger, resume)
+		alerts, err := dockermonitor.RunWithAlerts(ctx, c.monitor, workflows, c.cfg.StatusRules, engine)
+		// Like run, nothing is left to resume once every workflow went through.
+		if resume != nil && completed(workflows) {
+			if err := resume.Clear(); err != nil {
+				logger.Warn("clearing resume state", "error", err)
+			}
+		}
+		resume = nil
+		for _, alert := range alerts {
+			logger.Warn("alert", "rule", alert.Rule, "environment", alert.Environment, "severity", alert.Severity, "value", alert.Value)
+		}
+		if err != nil {
+			logger.Error("alert run failed", "error", err)
+		}
+		// Digests cover the time between scheduled runs.
+		if *interval > 0 {
+			digests, err := engine.SendDigests(ctx, c.monitor)
+			for _, digest := range digests {
+				logger.Info("event digest", "environment", digest.Environment, "started", len(digest.ContainersStarted),
+					"stopped", len(digest.ContainersStopped), "died", len(digest.ContainersDied), "pulled", len(digest.ImagesPulled))
+			}
+			if err != nil {
+				logger.Error("sending event digests", "error", err)
+			}
+		}
+		if *interval <= 0 {
+			if err != nil {
+				return exitActionFailed
+			}
+			if len(alerts) > 0 {
+				return exitConditionFailed
+			}
+			return exitOK
+		}
+		select {
+		case <-ctx.Done():
+			return exitOK
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// completed reports whether every action of the workflows ran, or was
+// skipped, without failing.
+func completed(workflows []*dockermonitor.Workflow) bool {
+	for _, w := range workflows {
+		if len(w.Results) != len(w.Actions) {
+			return false
+		}
+		for _, result := range w.Results {
+			if result.Status == dockermonitor.ActionFailed {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/adrien19/dockermonitor"
+)
+
+// runDiff compares two snapshots and prints what changed. Like diff(1), it
+// exits with status 1 when they differ.
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := flags.String("format", "text", "print the changes as text or json")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: dockermonitor diff [flags] OLD.json NEW.json")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return exitUsage
+	}
+
+	var snapshots [2][]dockermonitor.DockerEnvironment
+	for i, path := range flags.Args() {
+		var err error
+		if snapshots[i], err = dockermonitor.LoadSnapshot(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+	changes := dockermonitor.DiffSnapshots(snapshots[0], snapshots[1])
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if changes == nil {
+			changes = []dockermonitor.SnapshotChange{}
+		}
+		encoder.Encode(changes)
+	} else {
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+	}
+	if len(changes) > 0 {
+		return exitActionFailed
+	}
+	return exitOK
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/adrien19/dockermonitor"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Exit codes of the dockermonitor command.
+const (
+	exitOK              = 0
+	exitActionFailed    = 1
+	exitUsage           = 2
+	exitConditionFailed = 3
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// commands are the subcommands, each run with the arguments after its name.
+var commands = map[string]func(args []string) int{
+	"monitor":  runMonitor,
+	"serve":    runServe,
+	"snapshot": runSnapshot,
+	"diff":     runDiff,
+	"alert":    runAlert,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run dispatches to a subcommand. Without one, or when the first argument
+// is a flag, it runs monitor, so invocations from before the subcommands
+// keep working.
+func run(args []string) int {
+	name := "monitor"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return exitOK
+	}
+	command, found := commands[name]
+	if !found {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage(os.Stderr)
+		return exitUsage
+	}
+	return command(args)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprint(w, `Usage: dockermonitor [command] [flags]
+
+Commands:
+  monitor   run the actions once and print the results (the default)
+  serve     run the actions, then serve the JSON API and metrics
+  snapshot  run the actions and save the JSON output to a file
+  diff      compare two saved snapshots
+  alert     run the actions and send the alerts of the config's alert rules
+
+Run "dockermonitor <command> -h" for the flags of a command.
+`)
+}
+
+// collectFlags are the flags of every subcommand that runs the workflows.
+type collectFlags struct {
+	envFile        string
+	quiet          bool
+	stateFile      string
+	resume         bool
+	configPath     string
+	commandTimeout time.Duration
+	retries        int
+	retryBudget    int
+	only           string
+	actions        string
+	redact         stringList
+	logFile        string
+	logMaxSize     int
+	logMaxBackups  int
+	logMaxAge      int
+	progress       bool
+
+	// logWriter is the rotating log file, if any, closed with the collection.
+	logWriter io.Closer
+}
+
+func (f *collectFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.envFile, "env-file", ".env", "load KEY=VALUE environment variables from this file if it exists")
+	flags.BoolVar(&f.quiet, "quiet", false, "only log errors; stdout carries just the requested output")
+	flags.StringVar(&f.stateFile, "state-file", ".dockermonitor-state.json", "where succeeded actions are recorded for -resume")
+	flags.BoolVar(&f.resume, "resume", false, "skip actions that succeeded in the previous, incomplete run")
+	flags.StringVar(&f.configPath, "config", "", "JSON configuration file; defaults to a Dev and UAT environment")
+	flags.DurationVar(&f.commandTimeout, "command-timeout", 0, "kill any single docker command running longer than this, e.g. 30s; 0 means no limit")
+	flags.IntVar(&f.retries, "retries", 0, "run a failed action up to this many more times")
+	flags.IntVar(&f.retryBudget, "retry-budget", 30, "at most this many retries a minute across all environments; 0 means no limit")
+	flags.Var(&f.redact, "redact", "regular expression masked in the output, in addition to the config's redact patterns; repeatable")
+	flags.StringVar(&f.logFile, "log-file", "", "write logs to this file instead of stderr, rotating it by size")
+	flags.IntVar(&f.logMaxSize, "log-max-size", 100, "rotate the -log-file once it reaches this many megabytes")
+	flags.IntVar(&f.logMaxBackups, "log-max-backups", 5, "keep at most this many rotated log files; 0 keeps all")
+	flags.IntVar(&f.logMaxAge, "log-max-age", 0, "delete rotated log files older than this many days; 0 keeps them")
+	flags.BoolVar(&f.progress, "progress", false, "log \"completed X of Y environments\" as each environment's workflow finishes")
+	flags.StringVar(&f.only, "only", "", "comma-separated name or tag globs; only matching environments are monitored, e.g. 'prod*'")
+	flags.StringVar(&f.actions, "actions", "", "comma-separated action names; only these and the actions they depend on run, e.g. 'containers-status,local-images'")
+}
+
+// logger sends informational output to stderr, or the -log-file, so stdout
+// stays clean for the collected data.
+func (f *collectFlags) logger() *slog.Logger {
+	logLevel := slog.LevelInfo
+	if f.quiet {
+		logLevel = slog.LevelError
+	}
+	var w io.Writer = os.Stderr
+	if f.logFile != "" {
+		logFile := &lumberjack.Logger{
+			Filename:   f.logFile,
+			MaxSize:    f.logMaxSize,
+			MaxBackups: f.logMaxBackups,
+			MaxAge:     f.logMaxAge,
+		}
+		w, f.logWriter = logFile, logFile
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: logLevel}))
+}
+
+// collection is a configured monitor with the actions to run against every
+// environment.
+type collection struct {
+	cfg         *dockermonitor.Config
+	monitor     *dockermonitor.DockerMonitor
+	actions     []dockermonitor.Action
+	logger      *slog.Logger
+	logWriter   io.Closer
+	resumeState *dockermonitor.ResumeState
+	progress    bool
+}
+
+// setup loads the env file, resume state and config, and creates the
+// monitor and its actions.
+func (f *collectFlags) setup(logger *slog.Logger) (*collection, error) {
+	// Variables from the .env file must be in place before anything reads the environment.
+	if err := dockermonitor.LoadDotEnv(f.envFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("loading env file: %w", err)
+	}
+
+	// Progress is always recorded so a failed run can be resumed later; without
+	// -resume we start from scratch.
+	resumeState := dockermonitor.NewResumeState(f.stateFile)
+	if f.resume {
+		var err error
+		if resumeState, err = dockermonitor.LoadResumeState(f.stateFile); err != nil {
+			return nil, fmt.Errorf("loading resume state: %w", err)
+		}
+	}
+
+	cfg := dockermonitor.DefaultConfig()
+	if f.configPath != "" {
+		var err error
+		if cfg, err = dockermonitor.LoadConfig(f.configPath); err != nil {
+			return nil, fmt.Errorf("loading config: %w", err)
+		}
+	}
+	if patterns := splitList(f.only); len(patterns) > 0 {
+		if err := cfg.Select(patterns...); err != nil {
+			return nil, fmt.Errorf("selecting environments: %w", err)
+		}
+	}
+
+	d := cfg.NewMonitor()
+	d.Logger = logger
+	d.Quiet = f.quiet
+	d.CommandTimeout = f.commandTimeout
+	d.Retries = f.retries
+	if f.retryBudget > 0 {
+		d.RetryBudget = dockermonitor.NewRetryBudget(f.retryBudget)
+	}
+	if patterns := slices.Concat(cfg.Redact, f.redact); len(patterns) > 0 {
+		var err error
+		if d.Redaction, err = dockermonitor.NewRedactionPolicy(patterns...); err != nil {
+			return nil, err
+		}
+	}
+
+	// Here we assign actions we want to use for each environment.
+	// If we chose, we can pass in args in this methods. For example: configs.
+	actions := []dockermonitor.Action{
+		d.CallCapabilities(),
+		d.CallDockerVersion(),
+		d.CallDaemonConfig(),
+		d.CallHostResources(),
+		d.CallContainersStatus(),
+		d.CallContainerIPs(),
+		d.CallContainerEntrypointCmd(),
+		d.CallLocalImages(),
+		d.CallImageUsage(),
+		d.CallContainerImageMismatch(),
+		d.CallImageBaseOS(false),
+		d.CallImageProvenance(),
+		d.CallVolumeUsage(),
+		d.CallOrphanedContainers(),
+		d.CallCPUArchitecture(),
+		d.CallContainerUser(),
+		d.CallContainerReadOnlyRootfs(),
+		d.CallContainerCapabilities(),
+		d.CallContainerSecurityOpt(),
+		d.CallContainerNetworkMode(),
+		d.CallContainerDependsOn(),
+		d.CallMemoryLimits(),
+		d.CallContainerTmpfsMounts(),
+		d.CallContainerUlimits(0),
+		d.CallContainerLogDriver(cfg.LogDrivers...),
+		d.CallZombieProcesses(),
+		d.CallContainerHealthHistory(0, 0),
+		d.CallContainerCreatedVsStarted(0),
+		d.CallContainerStopTimeout(0),
+	}
+	if len(cfg.ExpectedPorts) > 0 {
+		actions = append(actions, d.CallPortBindings(cfg.ExpectedPorts))
+	}
+	if cfg.LabelPolicy != nil {
+		actions = append(actions, d.CallContainerLabelsPolicy(*cfg.LabelPolicy))
+	}
+	if cfg.Connectivity != nil {
+		actions = append(actions, d.CallContainerNetworkConnectivity(*cfg.Connectivity))
+	}
+	for _, env := range cfg.Environments {
+		if env.ComposeFile != "" {
+			actions = append(actions, d.CallComposeFileDrift())
+			break
+		}
+	}
+	if names := splitList(f.actions); len(names) > 0 {
+		var err error
+		if actions, err = dockermonitor.SelectActions(actions, names...); err != nil {
+			return nil, fmt.Errorf("selecting actions: %w", err)
+		}
+	}
+
+	return &collection{cfg: cfg, monitor: d, actions: actions, logger: logger, logWriter: f.logWriter, resumeState: resumeState, progress: f.progress}, nil
+}
+
+// run executes the workflows once and reports whether any of them failed.
+func (c *collection) run() bool {
+	d, logger := c.monitor, c.logger
+
+	// We can have also multiple workflows and each take in an array of actions to execute.
+	// other properties can also be used for scheduling or action sequencing as well.
+	// Here, I am using Name to cleary identify which workflow is run.
+	workflows := newWorkflows(d, c.cfg.EnvironmentNames(), c.actions, logger, c.resumeState)
+	c.trackProgress(workflows)
+
+	// Here, we loop through the workflows to execute the actions
+	// we return the error if we encounter one. We can also choose to break the loop if the
+	// workflow are dependent of each other.
+	failed := false
+	for index, w := range workflows {
+		logger.Info("workflow", "index", index, "name", w.Name)
+		err := w.ExecuteActions(context.Background())
+		if err != nil {
+			failed = true
+			logger.Error("workflow failed", "workflow", w.Name, "error", err)
+		}
+	}
+	d.ComputeStatus(c.cfg.StatusRules)
+
+	// Nothing left to resume once every workflow went through.
+	if !failed {
+		if err := c.resumeState.Clear(); err != nil {
+			logger.Warn("clearing resume state", "error", err)
+		}
+	}
+
+	// Since we create the instance of DockerMoinitor using NewDockerMonitor()
+	// We can access it's properties at anytime like below.
+	// The actions will update these properties, hence abstructing any execution details.
+	if len(d.DockerEnvironments) > 0 && len(d.DockerEnvironments[0].ContainersInfo) > 0 {
+		logger.Info("first container", "image", d.DockerEnvironments[0].ContainersInfo[0].Image)
+	}
+	for _, summary := range d.Summary() {
+		logger.Info("summary", "environment", summary.Environment, "status", summary.Status, "running", summary.Running,
+			"stopped", summary.Stopped, "unhealthy", summary.Unhealthy, "images", summary.Images, "reasons", summary.Reasons)
+	}
+	return failed
+}
+
+// trackProgress logs, with -progress, how many of workflows have finished.
+func (c *collection) trackProgress(workflows []*dockermonitor.Workflow) {
+	if !c.progress {
+		return
+	}
+	progress := dockermonitor.NewProgress(len(workflows), func(u dockermonitor.ProgressUpdate) {
+		c.logger.Info(fmt.Sprintf("completed %d of %d environments", u.Completed, u.Total), "environment", u.Environment, "failed", u.Err != nil)
+	})
+	for _, w := range workflows {
+		w.Progress = progress
+	}
+}
+
+// close shuts down the SSH connections of remote environments and closes the
+// log file.
+func (c *collection) close() {
+	if err := c.monitor.Close(); err != nil {
+		c.logger.Warn("closing ssh connections", "error", err)
+	}
+	if c.logWriter != nil {
+		c.logWriter.Close()
+	}
+}
+
+// newWorkflows creates one workflow per environment, each running actions.
+func newWorkflows(d *dockermonitor.DockerMonitor, envs []string, actions []dockermonitor.Action, logger *slog.Logger, resume *dockermonitor.ResumeState) []*dockermonitor.Workflow {
+	var workflows []*dockermonitor.Workflow
+	for _, env := range envs {
+		workflows = append(workflows, &dockermonitor.Workflow{
+			Name:    env,
+			Actions: actions,
+			Logger:  logger,
+			Resume:  resume,
+			Monitor: d,
+		})
+	}
+	return workflows
+}
+
+// templateFormatter parses the -template flag, which is mutually exclusive
+// with -format.
+func templateFormatter(text, format string) (dockermonitor.Formatter, error) {
+	if format != "" {
+		return nil, errors.New("-template and -format can't be used together")
+	}
+	if path, found := strings.CutPrefix(text, "@"); found {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	return dockermonitor.NewTemplateFormatter(text)
+}
+
+// pprofHandler serves the net/http/pprof handlers without touching
+// http.DefaultServeMux.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// splitList splits a comma-separated flag value, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
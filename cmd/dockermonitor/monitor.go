@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/adrien19/dockermonitor"
+)
+
+// runMonitor runs the actions once, writes the collected data to stdout in
+// the requested format and checks the -fail-on conditions.
+func runMonitor(args []string) int {
+	flags := flag.NewFlagSet("monitor", flag.ExitOnError)
+	var collect collectFlags
+	collect.register(flags)
+	var failOn stringList
+	flags.Var(&failOn, "fail-on", "exit with status 3 when a condition like unhealthy or stopped>5 holds in any environment; repeatable, all must pass")
+	format := flags.String("format", "", "write collected data to stdout as json, ndjson, ndjson-containers, csv, table, matrix, matrix-json, prom or influx")
+	compact := flags.Bool("compact", !isTerminal(os.Stdout), "write single-line JSON; defaults to true unless stdout is a terminal")
+	fields := flags.String("fields", "", "comma-separated container fields to output, e.g. id,names,state,image")
+	templateText := flags.String("template", "", "render collected data to stdout with this Go text/template instead of -format; @path reads it from a file")
+	keyCase := flags.String("key-case", "", "spell JSON keys in camel, snake or pascal case; by default keys keep their current names")
+	var outputSpecs stringList
+	flags.Var(&outputSpecs, "output", "also write the collected data as format:path, e.g. json:/tmp/out.json or table:- for stdout; repeatable")
+	flags.Parse(args)
+
+	var formatter dockermonitor.Formatter
+	if *templateText != "" || *format != "" {
+		var err error
+		if *templateText != "" {
+			formatter, err = templateFormatter(*templateText, *format)
+		} else {
+			var kc dockermonitor.KeyCase
+			if kc, err = dockermonitor.ParseKeyCase(*keyCase); err == nil {
+				formatter, err = dockermonitor.FormatterFor(*format, dockermonitor.FormatOptions{Compact: *compact, Fields: splitList(*fields), KeyCase: kc})
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+	}
+
+	var outputs []output
+	for _, spec := range outputSpecs {
+		o, err := parseOutput(spec, *compact, splitList(*fields), *keyCase)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		outputs = append(outputs, o)
+	}
+
+	var conditions []dockermonitor.FailCondition
+	for _, expr := range failOn {
+		condition, err := dockermonitor.ParseFailCondition(expr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		conditions = append(conditions, condition)
+	}
+
+	logger := collect.logger()
+	c, err := collect.setup(logger)
+	if err != nil {
+		logger.Error("setting up", "error", err)
+		return exitUsage
+	}
+	// Closed last, so the errors logged below still reach the -log-file.
+	defer c.close()
+	failed := c.run()
+
+	// Every output renders the same snapshot, redacted once.
+	snapshot := c.monitor.Snapshot()
+	snapshot.Redaction = nil
+	if formatter != nil {
+		outputs = append([]output{{formatter: formatter, path: "-"}}, outputs...)
+	}
+	outputFailed := false
+	for _, o := range outputs {
+		if err := o.write(snapshot); err != nil {
+			logger.Error("writing output", "path", o.path, "error", err)
+			outputFailed = true
+		}
+	}
+	if outputFailed {
+		return exitActionFailed
+	}
+
+	violations := dockermonitor.EvaluateFailConditions(c.monitor, conditions)
+	for _, violation := range violations {
+		logger.Error("fail-on condition met", "condition", violation)
+	}
+	switch {
+	case failed:
+		return exitActionFailed
+	case len(violations) > 0:
+		return exitConditionFailed
+	}
+	return exitOK
+}
+
+// output is a formatter and where it writes, a file or "-" for stdout.
+type output struct {
+	formatter dockermonitor.Formatter
+	path      string
+}
+
+// parseOutput parses an -output spec such as "json:/tmp/out.json" or
+// "table:-". The -compact, -fields and -key-case flags apply to every output.
+func parseOutput(spec string, compact bool, fields []string, keyCase string) (output, error) {
+	format, path, found := strings.Cut(spec, ":")
+	if !found || format == "" || path == "" {
+		return output{}, fmt.Errorf("-output %q: want format:path, e.g. json:out.json or table:-", spec)
+	}
+	kc, err := dockermonitor.ParseKeyCase(keyCase)
+	if err != nil {
+		return output{}, err
+	}
+	formatter, err := dockermonitor.FormatterFor(format, dockermonitor.FormatOptions{Compact: compact, Fields: fields, KeyCase: kc})
+	if err != nil {
+		return output{}, fmt.Errorf("-output %q: %w", spec, err)
+	}
+	return output{formatter: formatter, path: path}, nil
+}
+
+func (o output) write(d *dockermonitor.DockerMonitor) error {
+	if o.path == "-" {
+		return o.formatter.Format(os.Stdout, d)
+	}
+	return writeFile(o.path, func(w io.Writer) error {
+		return o.formatter.Format(w, d)
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+
+	"github.com/adrien19/dockermonitor"
+)
+
+// runServe runs the actions once, then serves the JSON API, metrics and
+// live updates until the server stops.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	var collect collectFlags
+	collect.register(flags)
+	listen := flags.String("listen", ":8080", "serve the JSON API on this address")
+	cacheTTL := flags.Duration("cache-ttl", 0, "API reads first refresh environments whose data is older than this, e.g. 30s; 0 only refreshes on POST /refresh")
+	pprofAddr := flags.String("pprof", "", "serve net/http/pprof under /debug/pprof/ on this separate address, e.g. localhost:6060")
+	flags.Parse(args)
+
+	logger := collect.logger()
+
+	// Profiling starts before collection so the first run can be profiled too.
+	// It gets its own address, never the API's, so it can stay bound to localhost.
+	if *pprofAddr != "" {
+		go func() {
+			logger.Info("serving pprof", "address", *pprofAddr)
+			if err := http.ListenAndServe(*pprofAddr, pprofHandler()); err != nil {
+				logger.Error("serving pprof", "error", err)
+			}
+		}()
+	}
+
+	c, err := collect.setup(logger)
+	if err != nil {
+		logger.Error("setting up", "error", err)
+		return exitUsage
+	}
+	// A failed first run is logged by run; refreshes may still succeed.
+	c.run()
+	// SSH connections stay open for POST /refresh.
+	defer c.close()
+
+	server := dockermonitor.NewServer(c.monitor, dockermonitor.WithCacheTTL(*cacheTTL))
+	// Refreshes always run every action; resume state only applies to the first run.
+	server.Refresh = func(ctx context.Context, envs ...string) error {
+		if len(envs) == 0 {
+			envs = c.cfg.EnvironmentNames()
+		}
+		var errs []error
+		workflows := newWorkflows(c.monitor, envs, c.actions, logger, nil)
+		c.trackProgress(workflows)
+		for _, w := range workflows {
+			errs = append(errs, w.ExecuteActions(ctx))
+		}
+		c.monitor.ComputeStatus(c.cfg.StatusRules)
+		return errors.Join(errs...)
+	}
+	logger.Info("serving API", "address", *listen)
+	err = http.ListenAndServe(*listen, server)
+	logger.Error("serving API", "error", err)
+	return exitUsage
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/adrien19/dockermonitor"
+)
+
+// runSnapshot runs the actions once and saves the collected data as JSON,
+// for diff to compare later.
+func runSnapshot(args []string) int {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	var collect collectFlags
+	collect.register(flags)
+	out := flags.String("o", "dockermonitor-snapshot.json", "write the snapshot to this file")
+	dir := flags.String("dir", "", "instead of -o, add a timestamped, gzip-compressed snapshot to this history directory")
+	compact := flags.Bool("compact", false, "write single-line JSON")
+	flags.Parse(args)
+
+	logger := collect.logger()
+	c, err := collect.setup(logger)
+	if err != nil {
+		logger.Error("setting up", "error", err)
+		return exitUsage
+	}
+	// Closed last, so the errors logged below still reach the -log-file.
+	defer c.close()
+	failed := c.run()
+
+	path := *out
+	if *dir != "" {
+		path, err = dockermonitor.NewSnapshotStore(*dir).Save(c.monitor)
+	} else {
+		err = writeSnapshot(path, c.monitor, *compact)
+	}
+	if err != nil {
+		logger.Error("writing snapshot", "error", err)
+		return exitActionFailed
+	}
+	logger.Info("saved snapshot", "path", path)
+	if failed {
+		return exitActionFailed
+	}
+	return exitOK
+}
+
+// writeSnapshot writes d's JSON output to path, see writeFile.
+func writeSnapshot(path string, d *dockermonitor.DockerMonitor, compact bool) error {
+	return writeFile(path, func(w io.Writer) error {
+		return (dockermonitor.JSONFormatter{Compact: compact}).Format(w, d)
+	})
+}
+
+// writeFile writes to path through a temporary file, so an existing file is
+// only replaced by a complete one.
+func writeFile(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,234 @@
+package dockermonitor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DockerCommand builds the docker invocation for an action running against
+// env; the process is killed once ctx is done or the monitor's command timeout
+// passes. Custom actions should use it so they honour the environment's host
+// and the monitor's global flags, and call release once the command has
+// finished, or was never started, to free what it holds. With a
+// MaxConcurrency, DockerCommand waits for one of the environment's slots
+// first; streaming commands, `docker events` without --until and `docker
+// logs -f`, don't take one since they would hold it for good.
+//
+// The final command line is assembled as
+//
+//	[ssh <multiplexing options> <host>] docker <GlobalArgs> <args>
+//
+// GlobalArgs are placed right after the docker binary and before the subcommand,
+// e.g. `docker --host tcp://10.0.0.5:2376 --tls container ls`, since docker only
+// accepts global flags in that position. Environments with a Host run docker on
+// that machine over SSH, and a SocketPath is passed to docker as DOCKER_HOST
+// there and locally alike.
+func (d *DockerMonitor) DockerCommand(ctx context.Context, env string, args ...string) (cmd *exec.Cmd, release func()) {
+	ctx, release = d.commandContext(ctx, env, d.commandTimeout(args), !streaming(args))
+
+	dockerArgs := make([]string, 0, len(d.GlobalArgs)+len(args))
+	dockerArgs = append(dockerArgs, d.GlobalArgs...)
+	dockerArgs = append(dockerArgs, args...)
+
+	var host, socketPath string
+	d.withEnvironment(env, func(e *DockerEnvironment) {
+		host = e.Host
+		socketPath = e.SocketPath
+	})
+	if host == "" {
+		cmd := exec.CommandContext(ctx, "docker", dockerArgs...)
+		if socketPath != "" {
+			cmd.Env = append(os.Environ(), "DOCKER_HOST=unix://"+socketPath)
+		}
+		return cmd, release
+	}
+
+	// ssh hands the remote command to a shell, so every argument is quoted.
+	sshArgs := append(d.sshControlOptions(env), host)
+	if socketPath != "" {
+		sshArgs = append(sshArgs, "env", shellQuote("DOCKER_HOST=unix://"+socketPath))
+	}
+	sshArgs = append(sshArgs, "docker")
+	for _, arg := range dockerArgs {
+		sshArgs = append(sshArgs, shellQuote(arg))
+	}
+	return exec.CommandContext(ctx, "ssh", sshArgs...), release
+}
+
+// hostCommand builds a command run on env's docker host itself rather than
+// through docker, e.g. to read /proc/meminfo: over SSH for environments with
+// a Host, locally otherwise. Callers make sure a local daemon really runs on
+// this machine, see runsLocally. Like DockerCommand, it returns the function
+// to call once the command has finished.
+func (d *DockerMonitor) hostCommand(ctx context.Context, env string, name string, args ...string) (cmd *exec.Cmd, release func()) {
+	ctx, release = d.commandContext(ctx, env, d.CommandTimeout, true)
+	var host string
+	d.withEnvironment(env, func(e *DockerEnvironment) {
+		host = e.Host
+	})
+	if host == "" {
+		return exec.CommandContext(ctx, name, args...), release
+	}
+	sshArgs := append(d.sshControlOptions(env), host, shellQuote(name))
+	for _, arg := range args {
+		sshArgs = append(sshArgs, shellQuote(arg))
+	}
+	return exec.CommandContext(ctx, "ssh", sshArgs...), release
+}
+
+// DockerOutput runs a read-only docker command built by DockerCommand and
+// returns its standard output. Identical calls for the same environment that
+// overlap share a single execution and its result, so bursts of actions or API
+// requests don't run the same command over and over. The shared command runs
+// with the first caller's ctx; later callers stop waiting once their own ctx is
+// done. Failures are returned like commandError, and the output must not be
+// modified since other callers hold it too.
+func (d *DockerMonitor) DockerOutput(ctx context.Context, env string, args ...string) ([]byte, error) {
+	key := env + "\x00" + strings.Join(args, "\x00")
+	calls := d.calls.DoChan(key, func() (any, error) {
+		cmd, release := d.DockerCommand(ctx, env, args...)
+		defer release()
+		out, err := cmd.Output()
+		if err != nil {
+			return out, commandError(cmd, err)
+		}
+		return out, nil
+	})
+	select {
+	case result := <-calls:
+		out, _ := result.Val.([]byte)
+		// The workflow fills in the action on the error, which differs per caller.
+		var actionErr *ActionError
+		if errors.As(result.Err, &actionErr) {
+			shared := *actionErr
+			return out, &shared
+		}
+		return out, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// commandContext takes one of env's command slots, if slot is set, then
+// bounds ctx by timeout unless it is zero. Release frees both; a ctx done
+// while waiting for a slot leaves the returned context done, so the command
+// fails to start.
+func (d *DockerMonitor) commandContext(ctx context.Context, env string, timeout time.Duration, slot bool) (context.Context, func()) {
+	free := func() {}
+	if slot {
+		var err error
+		if free, err = d.acquireSlot(ctx, env); err != nil {
+			return ctx, func() {}
+		}
+	}
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	return ctx, sync.OnceFunc(func() {
+		cancel()
+		free()
+	})
+}
+
+// commandTimeout picks the timeout for a docker invocation by its subcommand.
+// Streaming commands run until cancelled, so only an explicit CommandTimeouts
+// entry bounds them.
+func (d *DockerMonitor) commandTimeout(args []string) time.Duration {
+	if len(args) > 0 {
+		if timeout, found := d.CommandTimeouts[args[0]]; found {
+			return timeout
+		}
+	}
+	if streaming(args) {
+		return 0
+	}
+	return d.CommandTimeout
+}
+
+// streaming reports whether a docker invocation streams until cancelled:
+// `docker events` without --until, which returns at that time, and `docker
+// logs -f`.
+func streaming(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "events":
+		return !slices.ContainsFunc(args, func(arg string) bool { return arg == "--until" || strings.HasPrefix(arg, "--until=") })
+	case "logs":
+		return slices.Contains(args, "-f") || slices.Contains(args, "--follow")
+	}
+	return false
+}
+
+// sshControlOptions enables SSH connection multiplexing so every action for
+// the same environment reuses one connection instead of a new handshake each time.
+func (d *DockerMonitor) sshControlOptions(env string) []string {
+	persist := d.SSHControlPersist
+	if persist <= 0 {
+		persist = DefaultSSHControlPersist
+	}
+	return []string{
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + d.sshControlPath(env),
+		"-o", "ControlPersist=" + strconv.Itoa(int(persist.Seconds())),
+	}
+}
+
+// sshControlPath returns the per-environment control socket. The name is a
+// short hash because unix socket paths are limited to roughly 100 bytes.
+func (d *DockerMonitor) sshControlPath(env string) string {
+	d.sshOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "dockermon-ssh-")
+		if err != nil {
+			dir = os.TempDir()
+		}
+		d.sshControlDir = dir
+	})
+	sum := sha256.Sum256([]byte(env))
+	return filepath.Join(d.sshControlDir, hex.EncodeToString(sum[:6])+".sock")
+}
+
+// Close shuts down the SSH master connections opened for remote environments
+// and removes their control sockets.
+func (d *DockerMonitor) Close() error {
+	if d.sshControlDir == "" {
+		return nil
+	}
+	var errs []error
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		if dockerEnv.Host == "" {
+			continue
+		}
+		socket := d.sshControlPath(dockerEnv.Environment)
+		if _, err := os.Stat(socket); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+		// impact to you machine. Make sure you know the commands you are running.
+		if err := exec.Command("ssh", "-o", "ControlPath="+socket, "-O", "exit", dockerEnv.Host).Run(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if d.sshControlDir != os.TempDir() {
+		errs = append(errs, os.RemoveAll(d.sshControlDir))
+	}
+	return errors.Join(errs...)
+}
+
+// shellQuote single-quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,147 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UnmarshalJSON decodes a container as printed by `docker ps --format
+// '{{json .}}'` or as written by the JSON formatter. Keys already match
+// regardless of case, so this only smooths over the shapes that differ
+// between docker versions and docker-compatible CLIs: Labels as an object
+// instead of "k=v,k=v", Names, Mounts, Networks and Ports as arrays, numbers
+// instead of strings, and a Created timestamp where newer versions print
+// CreatedAt. Labels and Networks are sorted.
+func (c *ContainerInfo) UnmarshalJSON(data []byte) error {
+	type plain ContainerInfo
+	v := struct {
+		*plain
+		Labels       borrowedJSON `json:"labels"`
+		Names        borrowedJSON `json:"names"`
+		Mounts       borrowedJSON `json:"mounts"`
+		Networks     borrowedJSON `json:"networks"`
+		Ports        borrowedJSON `json:"ports"`
+		LocalVolumes borrowedJSON `json:"localVolumes"`
+		Size         borrowedJSON `json:"size"`
+		Created      borrowedJSON `json:"created"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	// encoding/json sorts map keys, but docker prints labels and networks
+	// from maps, in random order; sorting them keeps the output of unchanged
+	// containers byte-identical between runs.
+	c.Labels = sortedLabels(flexibleString(v.Labels, ","))
+	c.Names = strings.TrimPrefix(flexibleString(v.Names, ","), "/")
+	c.Mounts = flexibleString(v.Mounts, ",")
+	if c.Networks = flexibleString(v.Networks, ","); strings.Contains(c.Networks, ",") {
+		networks := strings.Split(c.Networks, ",")
+		sort.Strings(networks)
+		c.Networks = strings.Join(networks, ",")
+	}
+	c.Ports = flexibleString(v.Ports, ", ")
+	c.LocalVolumes = flexibleString(v.LocalVolumes, ",")
+	c.Size = flexibleString(v.Size, ",")
+	if c.CreatedAt == "" {
+		c.CreatedAt = flexibleTime(v.Created)
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes an image as printed by `docker images --format
+// '{{json .}}'` or as written by the JSON formatter; see
+// ContainerInfo.UnmarshalJSON. docker prints CreatedSince, which the JSON
+// output has always spelled createSince, and versions that dropped
+// VirtualSize get Size instead.
+func (i *ImageInfo) UnmarshalJSON(data []byte) error {
+	type plain ImageInfo
+	v := struct {
+		*plain
+		Containers   borrowedJSON `json:"containers"`
+		Size         borrowedJSON `json:"size"`
+		SharedSize   borrowedJSON `json:"sharedSize"`
+		UniqueSize   borrowedJSON `json:"uniqueSize"`
+		VirtualSize  borrowedJSON `json:"virtualSize"`
+		CreatedSince *string      `json:"createdSince"`
+		Created      borrowedJSON `json:"created"`
+	}{plain: (*plain)(i)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	i.Containers = flexibleString(v.Containers, ",")
+	i.Size = flexibleString(v.Size, ",")
+	i.SharedSize = flexibleString(v.SharedSize, ",")
+	i.UniqueSize = flexibleString(v.UniqueSize, ",")
+	i.VirtualSize = flexibleString(v.VirtualSize, ",")
+	if i.VirtualSize == "" {
+		i.VirtualSize = i.Size
+	}
+	if v.CreatedSince != nil && i.CreatedSince == "" {
+		i.CreatedSince = *v.CreatedSince
+	}
+	if i.CreatedAt == "" {
+		i.CreatedAt = flexibleTime(v.Created)
+	}
+	return nil
+}
+
+// borrowedJSON holds a value of the document being decoded without copying
+// it, unlike json.RawMessage, which keeps parsing containers and images at a
+// few allocations per line. It is only valid until the UnmarshalJSON that
+// decodes into it returns.
+type borrowedJSON []byte
+
+func (b *borrowedJSON) UnmarshalJSON(data []byte) error {
+	*b = data
+	return nil
+}
+
+// flexibleString renders a JSON value docker may print in several shapes as
+// the string current versions print: strings as they are, arrays of strings
+// joined with sep, objects as sorted "key=value" pairs joined with commas and
+// anything else, such as numbers, as its JSON text.
+func flexibleString(raw borrowedJSON, sep string) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ""
+	}
+	switch raw[0] {
+	case '"':
+		if bytes.IndexByte(raw, '\\') < 0 {
+			return string(raw[1 : len(raw)-1])
+		}
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+	case '[':
+		var items []string
+		if json.Unmarshal(raw, &items) == nil {
+			return strings.Join(items, sep)
+		}
+	case '{':
+		var m map[string]string
+		if json.Unmarshal(raw, &m) == nil {
+			pairs := make([]string, 0, len(m))
+			for key, value := range m {
+				pairs = append(pairs, key+"="+value)
+			}
+			sort.Strings(pairs)
+			return strings.Join(pairs, ",")
+		}
+	}
+	return string(raw)
+}
+
+// flexibleTime renders a Created value, a string or Unix seconds, like the
+// CreatedAt column of docker ps.
+func flexibleTime(raw borrowedJSON) string {
+	var seconds int64
+	if json.Unmarshal(raw, &seconds) == nil && seconds > 0 {
+		return time.Unix(seconds, 0).UTC().Format("2006-01-02 15:04:05 -0700 MST")
+	}
+	return flexibleString(raw, ",")
+}
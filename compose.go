@@ -0,0 +1,168 @@
+package dockermonitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Labels docker compose puts on the containers it creates.
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+)
+
+// ComposeImageMismatch is a service whose running container uses another
+// image than the compose file declares.
+type ComposeImageMismatch struct {
+	Service  string `json:"service"`
+	Declared string `json:"declared"`
+	Running  string `json:"running"`
+}
+
+// ComposeDrift compares a compose file with the running containers of its project.
+type ComposeDrift struct {
+	File    string `json:"file"`
+	Project string `json:"project"`
+	// Missing services are declared but have no running container.
+	Missing []string `json:"missing,omitempty"`
+	// Undeclared services run in the project but aren't in the file.
+	Undeclared      []string               `json:"undeclared,omitempty"`
+	ImageMismatches []ComposeImageMismatch `json:"imageMismatches,omitempty"`
+}
+
+// composeFile is the part of a compose file the drift check reads.
+type composeFile struct {
+	Name     string `yaml:"name"`
+	Services map[string]struct {
+		Image    string   `yaml:"image"`
+		Profiles []string `yaml:"profiles"`
+	} `yaml:"services"`
+}
+
+type CheckComposeFileDrift struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckComposeFileDrift) Name() string        { return "compose-file-drift" }
+func (c CheckComposeFileDrift) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute checks the environment's ComposeFile; environments without one
+// are left alone.
+func (c CheckComposeFileDrift) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	if dockerEnv.ComposeFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(dockerEnv.ComposeFile)
+	if err != nil {
+		return err
+	}
+	var file composeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", dockerEnv.ComposeFile, err)
+	}
+
+	drift := ComposeDrift{File: dockerEnv.ComposeFile, Project: composeProjectName(dockerEnv.ComposeFile, file.Name)}
+	running := make(map[string]ContainerInfo)
+	for _, cont := range dockerEnv.ContainersInfo {
+		labels := cont.LabelMap()
+		if cont.State != "running" || labels[composeProjectLabel] != drift.Project {
+			continue
+		}
+		service := labels[composeServiceLabel]
+		running[service] = cont
+		if _, declared := file.Services[service]; !declared && !slices.Contains(drift.Undeclared, service) {
+			drift.Undeclared = append(drift.Undeclared, service)
+		}
+	}
+
+	for name, service := range file.Services {
+		cont, found := running[name]
+		switch {
+		case found && service.Image != "":
+			declared := expandComposeVariables(service.Image)
+			if normalizeImageReference(declared) != normalizeImageReference(cont.Image) {
+				drift.ImageMismatches = append(drift.ImageMismatches, ComposeImageMismatch{Service: name, Declared: declared, Running: cont.Image})
+			}
+		// Services behind a profile only run when it's enabled.
+		case !found && len(service.Profiles) == 0:
+			drift.Missing = append(drift.Missing, name)
+		}
+	}
+	sort.Strings(drift.Missing)
+	sort.Strings(drift.Undeclared)
+	sort.Slice(drift.ImageMismatches, func(i, j int) bool {
+		return drift.ImageMismatches[i].Service < drift.ImageMismatches[j].Service
+	})
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.ComposeDrift = &drift
+	})
+	c.dockerMonitor.logger().Info("compose drift", "environment", env, "project", drift.Project,
+		"missing", len(drift.Missing), "undeclared", len(drift.Undeclared), "imageMismatches", len(drift.ImageMismatches))
+	return nil
+}
+
+// composeProjectName follows docker compose: the file's name, then
+// COMPOSE_PROJECT_NAME, then the name of the file's directory.
+func composeProjectName(path, name string) string {
+	if name == "" {
+		name = os.Getenv("COMPOSE_PROJECT_NAME")
+	}
+	if name == "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			name = filepath.Base(filepath.Dir(abs))
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, name)
+}
+
+// expandComposeVariables substitutes ${VAR}, ${VAR:-default} and
+// ${VAR-default} from the process environment as docker compose does.
+func expandComposeVariables(s string) string {
+	return os.Expand(s, func(name string) string {
+		if key, fallback, found := strings.Cut(name, ":-"); found {
+			if value := os.Getenv(key); value != "" {
+				return value
+			}
+			return fallback
+		}
+		if key, fallback, found := strings.Cut(name, "-"); found {
+			if value, set := os.LookupEnv(key); set {
+				return value
+			}
+			return fallback
+		}
+		return os.Getenv(name)
+	})
+}
+
+// normalizeImageReference makes "nginx", "nginx:latest" and
+// "docker.io/library/nginx:latest" compare equal.
+func normalizeImageReference(ref string) string {
+	ref = strings.TrimPrefix(ref, "docker.io/")
+	ref = strings.TrimPrefix(ref, "library/")
+	if strings.Contains(ref, "@") {
+		return ref
+	}
+	if i := strings.LastIndex(ref, ":"); i <= strings.LastIndex(ref, "/") {
+		ref += ":latest"
+	}
+	return ref
+}
@@ -0,0 +1,36 @@
+package dockermonitor
+
+import "context"
+
+// acquireSlot blocks until env has fewer than MaxConcurrency commands
+// running and returns the function that frees the slot again. Environments
+// without a limit never wait.
+func (d *DockerMonitor) acquireSlot(ctx context.Context, env string) (release func(), err error) {
+	var limit int
+	d.withEnvironment(env, func(e *DockerEnvironment) {
+		limit = e.MaxConcurrency
+	})
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	d.slotsMu.Lock()
+	if d.slots == nil {
+		d.slots = make(map[string]chan struct{})
+	}
+	slots := d.slots[env]
+	// A changed limit takes effect for new commands; running ones release
+	// into the semaphore they took their slot from.
+	if cap(slots) != limit {
+		slots = make(chan struct{}, limit)
+		d.slots[env] = slots
+	}
+	d.slotsMu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,167 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Config is the JSON configuration file. ${VAR} references are expanded from
+// the process environment (including a loaded .env file) before parsing.
+type Config struct {
+	Environments []EnvironmentConfig `json:"environments"`
+
+	// ExpectedPorts maps a container name or image repository to the port
+	// bindings it may publish, as "[hostPort:]containerPort[/protocol]".
+	ExpectedPorts map[string][]string `json:"expectedPorts,omitempty"`
+
+	// LabelPolicy, if set, is enforced on every container.
+	LabelPolicy *LabelPolicy `json:"labelPolicy,omitempty"`
+
+	// LogDrivers are the logging drivers containers are expected to use,
+	// e.g. ["fluentd"]; the daemon's default when empty.
+	LogDrivers []string `json:"logDrivers,omitempty"`
+
+	// Connectivity, if set, is probed from every running container.
+	Connectivity *ConnectivityProbe `json:"connectivity,omitempty"`
+
+	// StatusRules decide each environment's healthy/warning/critical Status.
+	StatusRules StatusRules `json:"statusRules"`
+
+	// Alerts, if set, configures the alert subcommand, see RunWithAlerts.
+	Alerts *AlertConfig `json:"alerts,omitempty"`
+
+	// Redact lists regular expressions masked in the output, see
+	// RedactionPolicy.
+	Redact []string `json:"redact,omitempty"`
+}
+
+// EnvironmentConfig describes one monitored environment.
+type EnvironmentConfig struct {
+	Name string `json:"name"`
+	// Host is an optional SSH destination docker is run on.
+	Host string `json:"host,omitempty"`
+	// SocketPath is an optional daemon socket, e.g. "${XDG_RUNTIME_DIR}/docker.sock"
+	// for rootless docker.
+	SocketPath string `json:"socketPath,omitempty"`
+	// MaxConcurrency optionally caps how many docker commands run against
+	// the environment at once, e.g. 1 for a host behind a slow link.
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+	// ComposeFile is an optional docker-compose.yml to check for drift.
+	ComposeFile string `json:"composeFile,omitempty"`
+	// Tags group environments for selection with Select, e.g. "production".
+	Tags []string `json:"tags,omitempty"`
+}
+
+// DefaultConfig is used when no configuration file is given.
+func DefaultConfig() *Config {
+	return &Config{
+		Environments: []EnvironmentConfig{
+			{Name: "Dev Environment"},
+			{Name: "UAT Environment"},
+		},
+	}
+}
+
+// LoadConfig reads and validates the configuration file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	expanded := os.ExpandEnv(string(data))
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(expanded)))
+	decoder.DisallowUnknownFields()
+	var cfg Config
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(cfg.Environments) == 0 {
+		return nil, fmt.Errorf("%s: no environments configured", path)
+	}
+	if _, err := NewRedactionPolicy(cfg.Redact...); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.Connectivity != nil {
+		if _, err := cfg.Connectivity.command(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if cfg.Alerts != nil {
+		if _, err := NewAlertEngine(*cfg.Alerts); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, env := range cfg.Environments {
+		if env.Name == "" {
+			return nil, fmt.Errorf("%s: environment without a name", path)
+		}
+		if env.MaxConcurrency < 0 {
+			return nil, fmt.Errorf("%s: %s: maxConcurrency must not be negative", path, env.Name)
+		}
+	}
+	return &cfg, nil
+}
+
+// Select keeps only the environments whose name or one of whose tags matches
+// any of the glob patterns (see path.Match). It fails when nothing matches.
+func (c *Config) Select(patterns ...string) error {
+	var selected []EnvironmentConfig
+	for _, env := range c.Environments {
+		matched, err := env.matches(patterns)
+		if err != nil {
+			return err
+		}
+		if matched {
+			selected = append(selected, env)
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("no environment matches %s", strings.Join(patterns, ", "))
+	}
+	c.Environments = selected
+	return nil
+}
+
+func (e EnvironmentConfig) matches(patterns []string) (bool, error) {
+	for _, pattern := range patterns {
+		for _, candidate := range append([]string{e.Name}, e.Tags...) {
+			matched, err := path.Match(pattern, candidate)
+			if err != nil {
+				return false, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// EnvironmentNames lists the configured environment names in order.
+func (c *Config) EnvironmentNames() []string {
+	var names []string
+	for _, env := range c.Environments {
+		names = append(names, env.Name)
+	}
+	return names
+}
+
+// NewMonitor creates a DockerMonitor for the configured environments.
+func (c *Config) NewMonitor() *DockerMonitor {
+	d := NewDockerMonitor(c.EnvironmentNames())
+	for _, env := range c.Environments {
+		d.UpdateEnvironment(env.Name, func(e *DockerEnvironment) {
+			e.Host = env.Host
+			e.SocketPath = env.SocketPath
+			e.MaxConcurrency = env.MaxConcurrency
+			e.ComposeFile = env.ComposeFile
+			e.Tags = append([]string(nil), env.Tags...)
+		})
+	}
+	return d
+}
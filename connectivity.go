@@ -0,0 +1,106 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"time"
+)
+
+// Values of ContainerInfo.Connectivity.
+const (
+	ConnectivityReachable   = "reachable"
+	ConnectivityUnreachable = "unreachable"
+	// ConnectivityNoProbe means the container lacks the probe's tool.
+	ConnectivityNoProbe = "no-probe"
+)
+
+// probeTimeout bounds a single probe, so a container whose network silently
+// drops packets doesn't hold up the others.
+const probeTimeout = 10 * time.Second
+
+// ConnectivityProbe describes how running containers check that they reach a
+// dependency.
+type ConnectivityProbe struct {
+	// Target is the "host:port" probed with `nc -z` when Command is empty.
+	Target string `json:"target,omitempty"`
+	// Command, if set, is run in the container instead, e.g.
+	// ["wget", "-q", "-O", "/dev/null", "http://api:8080/health"]; exit
+	// status 0 means reachable.
+	Command []string `json:"command,omitempty"`
+}
+
+func (p ConnectivityProbe) command() ([]string, error) {
+	if len(p.Command) > 0 {
+		return p.Command, nil
+	}
+	host, port, err := net.SplitHostPort(p.Target)
+	if err != nil {
+		return nil, fmt.Errorf("connectivity probe target: %w", err)
+	}
+	return []string{"nc", "-z", "-w", "3", host, port}, nil
+}
+
+type CheckContainerNetworkConnectivity struct {
+	dockerMonitor *DockerMonitor
+	Probe         ConnectivityProbe
+}
+
+func (c CheckContainerNetworkConnectivity) Name() string { return "container-network-connectivity" }
+func (c CheckContainerNetworkConnectivity) DependsOn() []string {
+	return []string{"containers-status"}
+}
+
+// Execute runs the probe in every running container with `docker exec`,
+// which catches network policy and DNS problems a running state hides.
+// Containers whose image lacks the probe's tool are marked ConnectivityNoProbe
+// and not flagged.
+func (c CheckContainerNetworkConnectivity) Execute(ctx context.Context, env string) error {
+	probe, err := c.Probe.command()
+	if err != nil {
+		return err
+	}
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	results := make(map[string]string)
+	var unreachable []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		if cont.State != "running" {
+			continue
+		}
+		probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+		// impact to you machine. Make sure you know the commands you are running.
+		cmd, release := c.dockerMonitor.DockerCommand(probeCtx, env, append([]string{"exec", cont.ID}, probe...)...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		release()
+		cancel()
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			results[cont.ID] = ConnectivityReachable
+		case environmentDown(commandErrorWithStderr(cmd, err, stderr.Bytes())):
+			return commandErrorWithStderr(cmd, err, stderr.Bytes())
+		// docker exec exits with 126 or 127 when the command can't be run.
+		case errors.As(err, &exitErr) && (exitErr.ExitCode() == 126 || exitErr.ExitCode() == 127):
+			results[cont.ID] = ConnectivityNoProbe
+		default:
+			results[cont.ID] = ConnectivityUnreachable
+			unreachable = append(unreachable, cont.Names)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].Connectivity = results[e.ContainersInfo[i].ID]
+		}
+		e.UnreachableContainers = unreachable
+	})
+	c.dockerMonitor.logger().Info("container network connectivity", "environment", env, "probed", len(results), "unreachable", len(unreachable))
+	return nil
+}
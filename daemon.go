@@ -0,0 +1,68 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// DaemonInfo holds daemon-level settings that explain why the same container
+// behaves differently across hosts.
+type DaemonInfo struct {
+	ServerVersion      string `json:"serverVersion"`
+	StorageDriver      string `json:"storageDriver"`
+	LoggingDriver      string `json:"loggingDriver"`
+	CgroupDriver       string `json:"cgroupDriver"`
+	CgroupVersion      string `json:"cgroupVersion"`
+	DefaultRuntime     string `json:"defaultRuntime"`
+	LiveRestoreEnabled bool   `json:"liveRestoreEnabled"`
+	SwarmMode          bool   `json:"swarmMode"`
+}
+
+type CheckDaemonConfig struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckDaemonConfig) Name() string { return "daemon-config" }
+
+func (c CheckDaemonConfig) Execute(ctx context.Context, env string) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "info", "--format", "{{json .}}")
+	if err != nil {
+		return err
+	}
+	var info struct {
+		ServerVersion      string
+		Driver             string
+		LoggingDriver      string
+		CgroupDriver       string
+		CgroupVersion      string
+		DefaultRuntime     string
+		LiveRestoreEnabled bool
+		Swarm              struct {
+			LocalNodeState string
+		}
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return err
+	}
+	daemon := DaemonInfo{
+		ServerVersion:      info.ServerVersion,
+		StorageDriver:      info.Driver,
+		LoggingDriver:      info.LoggingDriver,
+		CgroupDriver:       info.CgroupDriver,
+		CgroupVersion:      info.CgroupVersion,
+		DefaultRuntime:     info.DefaultRuntime,
+		LiveRestoreEnabled: info.LiveRestoreEnabled,
+		SwarmMode:          info.Swarm.LocalNodeState == "active",
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.Daemon = &daemon
+	})
+	c.dockerMonitor.logger().Info("daemon config", "environment", env, "storageDriver", daemon.StorageDriver,
+		"loggingDriver", daemon.LoggingDriver, "cgroupVersion", daemon.CgroupVersion, "liveRestore", daemon.LiveRestoreEnabled,
+		"defaultRuntime", daemon.DefaultRuntime, "swarm", daemon.SwarmMode)
+	return nil
+}
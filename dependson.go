@@ -0,0 +1,127 @@
+package dockermonitor
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// composeDependsOnLabel holds a service's depends_on entries as comma
+// separated "service:condition:restart" triples.
+const composeDependsOnLabel = "com.docker.compose.depends_on"
+
+// UnmetDependency is a running container whose compose dependency isn't up.
+// State is the dependency's container state, or "missing" without one.
+type UnmetDependency struct {
+	Container  string `json:"container"`
+	Dependency string `json:"dependency"`
+	State      string `json:"state"`
+}
+
+// composeDependency is one depends_on entry of a compose service.
+type composeDependency struct {
+	service, condition string
+}
+
+// composeDependencies parses a container's depends_on label. Containers
+// created without docker compose, or by versions before 2.22, have none.
+func composeDependencies(labels map[string]string) []composeDependency {
+	var dependencies []composeDependency
+	for _, entry := range strings.Split(labels[composeDependsOnLabel], ",") {
+		service, rest, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		condition, _, _ := strings.Cut(rest, ":")
+		if service != "" {
+			dependencies = append(dependencies, composeDependency{service, condition})
+		}
+	}
+	return dependencies
+}
+
+// serviceContainers returns the containers of a compose service, by project.
+func (e *DockerEnvironment) serviceContainers() map[[2]string][]ContainerInfo {
+	services := make(map[[2]string][]ContainerInfo)
+	for _, cont := range e.ContainersInfo {
+		labels := cont.LabelMap()
+		if service := labels[composeServiceLabel]; service != "" {
+			key := [2]string{labels[composeProjectLabel], service}
+			services[key] = append(services[key], cont)
+		}
+	}
+	return services
+}
+
+// DependencyGraph maps each compose container's name to the names of the
+// containers its service depends on, sorted, from the depends_on labels
+// CheckContainersStatus collected. Dependencies on services without any
+// container are left out; CheckContainerDependsOn reports those.
+func (e *DockerEnvironment) DependencyGraph() map[string][]string {
+	services := e.serviceContainers()
+	graph := make(map[string][]string)
+	for _, cont := range e.ContainersInfo {
+		labels := cont.LabelMap()
+		dependencies := composeDependencies(labels)
+		if len(dependencies) == 0 {
+			continue
+		}
+		names := []string{}
+		for _, dependency := range dependencies {
+			for _, dep := range services[[2]string{labels[composeProjectLabel], dependency.service}] {
+				names = append(names, dep.Names)
+			}
+		}
+		sort.Strings(names)
+		graph[cont.Names] = names
+	}
+	return graph
+}
+
+type CheckContainerDependsOn struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerDependsOn) Name() string        { return "container-depends-on" }
+func (c CheckContainerDependsOn) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute lists running containers with a compose dependency that has no
+// running container, the usual cause of a service failing to reach its
+// database. A dependency with the service_completed_successfully condition
+// is met by a container that exited with code 0.
+func (c CheckContainerDependsOn) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	services := dockerEnv.serviceContainers()
+
+	var unmet []UnmetDependency
+	for _, cont := range dockerEnv.ContainersInfo {
+		if cont.State != "running" {
+			continue
+		}
+		labels := cont.LabelMap()
+		for _, dependency := range composeDependencies(labels) {
+			state := "missing"
+			for _, dep := range services[[2]string{labels[composeProjectLabel], dependency.service}] {
+				state = dep.State
+				if dependencyMet(dep, dependency.condition) {
+					state = ""
+					break
+				}
+			}
+			if state != "" {
+				unmet = append(unmet, UnmetDependency{Container: cont.Names, Dependency: dependency.service, State: state})
+			}
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.UnmetDependencies = unmet
+	})
+	c.dockerMonitor.logger().Info("container dependencies", "environment", env, "unmet", len(unmet))
+	return nil
+}
+
+// dependencyMet reports whether dep satisfies a depends_on condition.
+func dependencyMet(dep ContainerInfo, condition string) bool {
+	if condition == "service_completed_successfully" {
+		return dep.State == "exited" && strings.HasPrefix(dep.Status, "Exited (0)")
+	}
+	return dep.State == "running"
+}
@@ -0,0 +1,175 @@
+package dockermonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventDigest summarizes what happened on an environment between two runs.
+type EventDigest struct {
+	Environment       string    `json:"environment"`
+	Since             time.Time `json:"since"`
+	Until             time.Time `json:"until"`
+	ContainersStarted []string  `json:"containersStarted,omitempty"`
+	ContainersStopped []string  `json:"containersStopped,omitempty"`
+	ContainersDied    []string  `json:"containersDied,omitempty"`
+	ImagesPulled      []string  `json:"imagesPulled,omitempty"`
+	// tags are the environment's, for routing.
+	tags []string
+}
+
+// Empty reports whether nothing worth telling happened.
+func (g EventDigest) Empty() bool {
+	return len(g.ContainersStarted) == 0 && len(g.ContainersStopped) == 0 && len(g.ContainersDied) == 0 && len(g.ImagesPulled) == 0
+}
+
+func (g EventDigest) String() string {
+	var parts []string
+	for _, part := range []struct {
+		verb  string
+		names []string
+	}{
+		{"started", g.ContainersStarted},
+		{"stopped", g.ContainersStopped},
+		{"died", g.ContainersDied},
+		{"pulled", g.ImagesPulled},
+	} {
+		if len(part.names) > 0 {
+			parts = append(parts, part.verb+" "+strings.Join(part.names, ", "))
+		}
+	}
+	return fmt.Sprintf("%s since %s: %s", g.Environment, g.Since.Format(time.RFC3339), strings.Join(parts, "; "))
+}
+
+// SummarizeEvents builds the digest of events, listing each container or
+// image once per kind of event.
+func SummarizeEvents(env string, since, until time.Time, events []EventInfo) EventDigest {
+	digest := EventDigest{Environment: env, Since: since, Until: until}
+	add := func(names *[]string, name string) {
+		if name != "" && !slices.Contains(*names, name) {
+			*names = append(*names, name)
+		}
+	}
+	for _, event := range events {
+		name := event.Actor.Attributes["name"]
+		switch {
+		case event.Type == "container" && event.Action == "start":
+			add(&digest.ContainersStarted, name)
+		case event.Type == "container" && event.Action == "stop":
+			add(&digest.ContainersStopped, name)
+		case event.Type == "container" && event.Action == "die":
+			add(&digest.ContainersDied, name)
+		case event.Type == "image" && event.Action == "pull":
+			add(&digest.ImagesPulled, event.Actor.ID)
+		}
+	}
+	for _, names := range [][]string{digest.ContainersStarted, digest.ContainersStopped, digest.ContainersDied, digest.ImagesPulled} {
+		slices.Sort(names)
+	}
+	return digest
+}
+
+// EventsSince returns the events docker recorded for env between since and
+// until. Unlike StreamEvents it returns once they are read.
+func (d *DockerMonitor) EventsSince(ctx context.Context, env string, since, until time.Time) ([]EventInfo, error) {
+	out, err := d.DockerOutput(ctx, env, "events", "--since", strconv.FormatInt(since.Unix(), 10),
+		"--until", strconv.FormatInt(until.Unix(), 10), "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+	var events []EventInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		var event EventInfo
+		if err := unmarshalLine(line, "EventInfo", &event); err != nil {
+			d.logger().Warn("skipping malformed docker event", "environment", env, "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// DigestNotifier delivers event digests, for notifiers configured with
+// digest set.
+type DigestNotifier interface {
+	NotifyDigest(ctx context.Context, digests []EventDigest) error
+}
+
+// SendDigests summarizes every environment's events since the engine's
+// previous call and sends the digests that aren't empty to the notifiers
+// configured with digest. An environment's first call only marks where its
+// next digest starts, and nothing is collected while no notifier wants
+// digests. Environments whose digest couldn't be collected or delivered are
+// covered again by the next call, like alerts in RunWithAlerts. It returns
+// the digests that weren't empty.
+func (a *AlertEngine) SendDigests(ctx context.Context, d *DockerMonitor) ([]EventDigest, error) {
+	wanted := slices.ContainsFunc(a.routes, func(r alertRoute) bool { return r.digest })
+	until := time.Now()
+
+	var errs []error
+	var digests []EventDigest
+	// retry are the environments whose events the next digest covers again.
+	retry := make(map[string]bool)
+	environments := d.Snapshot().DockerEnvironments
+	for _, dockerEnv := range environments {
+		env := dockerEnv.Environment
+		since, found := a.digestSince[env]
+		if !found || !wanted {
+			continue
+		}
+		events, err := d.EventsSince(ctx, env, since, until)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: events: %w", env, err))
+			retry[env] = true
+			continue
+		}
+		if digest := SummarizeEvents(env, since, until, events); !digest.Empty() {
+			digest.tags = dockerEnv.Tags
+			digests = append(digests, digest)
+		}
+	}
+
+	for _, route := range a.routes {
+		if !route.digest {
+			continue
+		}
+		var routed []EventDigest
+		for _, digest := range digests {
+			if len(route.tags) == 0 || sharesTag(route.tags, digest.tags) {
+				routed = append(routed, digest)
+			}
+		}
+		if len(routed) == 0 {
+			continue
+		}
+		if err := route.notifier.(DigestNotifier).NotifyDigest(ctx, routed); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %s: %w", route.name, err))
+			for _, digest := range routed {
+				retry[digest.Environment] = true
+			}
+		}
+	}
+
+	for _, dockerEnv := range environments {
+		if !retry[dockerEnv.Environment] {
+			a.digestSince[dockerEnv.Environment] = until
+		}
+	}
+	return digests, errors.Join(errs...)
+}
+
+func digestText(digests []EventDigest) string {
+	lines := make([]string, len(digests))
+	for i, digest := range digests {
+		lines[i] = digest.String()
+	}
+	return strings.Join(lines, "\n")
+}
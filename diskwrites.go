@@ -0,0 +1,97 @@
+package dockermonitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultMaxDiskWriteRate is the write rate, in bytes per second,
+// CheckContainerDiskWrites flags by default.
+const DefaultMaxDiskWriteRate = 10 << 20
+
+// CheckContainerDiskWrites remembers the block I/O write totals of running
+// containers across runs, like CheckContainerHealthHistory, sets
+// DiskWriteBytesPerSecond on their stats from the totals of the previous
+// run, and lists those writing faster than MaxWriteRate as HeavyDiskWriters:
+// a runaway log shows up long before the disk is full. A container's first
+// run, or first run after a restart, only records its total.
+type CheckContainerDiskWrites struct {
+	dockerMonitor *DockerMonitor
+	// MaxWriteRate is in bytes per second; DefaultMaxDiskWriteRate when zero.
+	MaxWriteRate float64
+	history      *diskWriteHistory
+}
+
+type diskWriteSample struct {
+	at    time.Time
+	bytes int64
+}
+
+// diskWriteHistory holds the previous sample of every container, keyed by
+// environment and container ID.
+type diskWriteHistory struct {
+	mu      sync.Mutex
+	samples map[[2]string]diskWriteSample
+}
+
+func (c CheckContainerDiskWrites) Name() string        { return "container-disk-writes" }
+func (c CheckContainerDiskWrites) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerDiskWrites) Execute(ctx context.Context, env string) error {
+	if err := c.dockerMonitor.requireCapability(env, CapabilityStats); err != nil {
+		return err
+	}
+	maxRate := c.MaxWriteRate
+	if maxRate <= 0 {
+		maxRate = DefaultMaxDiskWriteRate
+	}
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	stats, err := c.dockerMonitor.sampleStats(ctx, env, runningContainerIDs(dockerEnv))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	h := c.history
+	h.mu.Lock()
+	for key := range h.samples {
+		if _, found := stats[key[1]]; key[0] == env && !found {
+			delete(h.samples, key)
+		}
+	}
+	rates := make(map[string]float64)
+	for id, s := range stats {
+		key := [2]string{env, id}
+		previous, found := h.samples[key]
+		h.samples[key] = diskWriteSample{at: now, bytes: s.BlockWriteBytes}
+		// Totals restart from zero when the container does.
+		if elapsed := now.Sub(previous.at).Seconds(); found && elapsed > 0 && s.BlockWriteBytes >= previous.bytes {
+			rates[id] = float64(s.BlockWriteBytes-previous.bytes) / elapsed
+		}
+	}
+	h.mu.Unlock()
+
+	var heavy []string
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i, cont := range e.ContainersInfo {
+			sample, sampled := stats[cont.ID]
+			if !sampled {
+				continue
+			}
+			if cont.Stats != nil {
+				sample = *cont.Stats
+			}
+			rate, known := rates[cont.ID]
+			sample.DiskWriteBytesPerSecond = rate
+			e.ContainersInfo[i].Stats = &sample
+			if known && rate > maxRate {
+				heavy = append(heavy, fmt.Sprintf("%s (%s/s)", cont.Names, humanBytes(int64(rate))))
+			}
+		}
+		e.HeavyDiskWriters = heavy
+	})
+	c.dockerMonitor.logger().Info("container disk writes", "environment", env, "heavy", len(heavy))
+	return nil
+}
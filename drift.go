@@ -0,0 +1,118 @@
+package dockermonitor
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// containerConfig is the subset of `docker inspect` Config shared by containers and images.
+type containerConfig struct {
+	Entrypoint stringOrSlice
+	Cmd        stringOrSlice
+	Env        []string
+	Volumes    map[string]struct{}
+	User       string
+	WorkingDir string
+}
+
+type CheckContainerUpdatedConfig struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerUpdatedConfig) Name() string        { return "container-config-drift" }
+func (c CheckContainerUpdatedConfig) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute compares every container's entrypoint, command, environment and
+// mounts with the defaults of the image it was created from, so manually
+// tweaked containers stand out from ones matching their definition.
+func (c CheckContainerUpdatedConfig) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, `{"config":{{json .Config}},"mounts":{{json .Mounts}},"image":{{json .Image}}}`)
+	if err != nil {
+		return err
+	}
+
+	type containerDetails struct {
+		Config containerConfig `json:"config"`
+		Mounts []struct {
+			Destination string
+		} `json:"mounts"`
+		Image string `json:"image"`
+	}
+	details := make(map[string]containerDetails)
+	var imageIDs []string
+	for id, raw := range inspected {
+		var d containerDetails
+		if err := unmarshalLine(raw, "containerDetails", &d); err != nil {
+			return err
+		}
+		details[id] = d
+		if !slices.Contains(imageIDs, d.Image) {
+			imageIDs = append(imageIDs, d.Image)
+		}
+	}
+
+	inspectedImages, err := c.dockerMonitor.inspect(ctx, env, imageIDs, "{{json .Config}}")
+	if err != nil {
+		return err
+	}
+
+	drift := make(map[string]string)
+	for id, d := range details {
+		raw, found := inspectedImages[d.Image]
+		if !found {
+			continue
+		}
+		var image containerConfig
+		if err := unmarshalLine(raw, "containerConfig", &image); err != nil {
+			return err
+		}
+
+		var changes []string
+		if !slices.Equal(d.Config.Entrypoint, image.Entrypoint) {
+			changes = append(changes, "custom entrypoint")
+		}
+		if !slices.Equal(d.Config.Cmd, image.Cmd) {
+			changes = append(changes, "overridden command")
+		}
+		// Only variable names are reported; values may hold secrets.
+		var envKeys []string
+		for _, kv := range d.Config.Env {
+			if !slices.Contains(image.Env, kv) {
+				key, _, _ := strings.Cut(kv, "=")
+				envKeys = append(envKeys, key)
+			}
+		}
+		if len(envKeys) > 0 {
+			changes = append(changes, "env "+strings.Join(envKeys, ","))
+		}
+		var mounts []string
+		for _, m := range d.Mounts {
+			if _, declared := image.Volumes[m.Destination]; !declared {
+				mounts = append(mounts, m.Destination)
+			}
+		}
+		if len(mounts) > 0 {
+			changes = append(changes, "mounts "+strings.Join(mounts, ","))
+		}
+		if len(changes) > 0 {
+			drift[id] = strings.Join(changes, "; ")
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			description, drifted := drift[e.ContainersInfo[i].ID]
+			e.ContainersInfo[i].ConfigDrift = drifted
+			e.ContainersInfo[i].ConfigDriftDescription = description
+		}
+	})
+	c.dockerMonitor.logger().Info("container config drift", "environment", env, "drifted", len(drift))
+	return nil
+}
@@ -0,0 +1,66 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// stringOrSlice decodes inspect fields like Entrypoint and Cmd, which older
+// daemons and hand-written configs may hold as a single shell string
+// instead of an array.
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = nil
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = stringOrSlice{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(s))
+}
+
+type CheckContainerEntrypointCmd struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerEntrypointCmd) Name() string        { return "container-entrypoint-cmd" }
+func (c CheckContainerEntrypointCmd) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records the full entrypoint and command of every container; the
+// Command column of `docker ps` is truncated and quoted.
+func (c CheckContainerEntrypointCmd) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .Config}}")
+	if err != nil {
+		return err
+	}
+
+	configs := make(map[string]containerConfig)
+	for id, raw := range inspected {
+		var config containerConfig
+		if err := unmarshalLine(raw, "containerConfig", &config); err != nil {
+			return err
+		}
+		configs[id] = config
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			if config, ok := configs[e.ContainersInfo[i].ID]; ok {
+				e.ContainersInfo[i].Entrypoint = config.Entrypoint
+				e.ContainersInfo[i].Cmd = config.Cmd
+			}
+		}
+	})
+	c.dockerMonitor.logger().Info("container entrypoints", "environment", env, "containers", len(configs))
+	return nil
+}
@@ -0,0 +1,174 @@
+package dockermonitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ActionError is returned for every failed action and tells which
+// environment, action and, when known, docker command failed. Use errors.As
+// to route failures per environment and errors.Is/Unwrap for the cause.
+type ActionError struct {
+	Environment string
+	Action      string
+	Command     string
+	Err         error
+}
+
+func (e *ActionError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", e.Environment, e.Action)
+	if e.Command != "" {
+		fmt.Fprintf(&b, ": %s", e.Command)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+func (e *ActionError) Unwrap() error {
+	return e.Err
+}
+
+// ErrHostUnreachable wraps failures of the SSH connection to a remote
+// environment, as opposed to errors of the docker command run over it. Once
+// an action fails with it, the workflow skips the environment's remaining
+// actions instead of failing each of them the same way.
+var ErrHostUnreachable = errors.New("host unreachable")
+
+// ErrDaemonNotRunning wraps failures of docker commands that couldn't reach
+// the docker daemon of an environment, typically because it is installed but
+// not started. The workflow treats it like ErrHostUnreachable, and the
+// ActionError wrapping it names the environment.
+var ErrDaemonNotRunning = errors.New("docker daemon not running")
+
+// daemonNotRunningMessage is what the docker CLI prints when it can't reach
+// the daemon's socket.
+const daemonNotRunningMessage = "Cannot connect to the Docker daemon"
+
+// commandError records which command produced err. The workflow fills in the
+// environment and action once the error reaches it.
+func commandError(cmd *exec.Cmd, err error) error {
+	if reason, failed := sshConnectionFailure(cmd, err); failed {
+		err = fmt.Errorf("%w: %s: %w", ErrHostUnreachable, reason, err)
+	} else if reason, failed := daemonFailure(err); failed {
+		err = fmt.Errorf("%w, start it (e.g. systemctl start docker) and try again: %s: %w", ErrDaemonNotRunning, reason, err)
+	}
+	return &ActionError{Command: strings.Join(cmd.Args, " "), Err: err}
+}
+
+// commandErrorWithStderr is commandError for commands whose stderr the
+// caller captured itself, which the exec.ExitError then lacks: stderr is
+// what the command wrote there.
+func commandErrorWithStderr(cmd *exec.Cmd, err error, stderr []byte) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+		exitErr.Stderr = stderr
+	}
+	return commandError(cmd, err)
+}
+
+// environmentDown reports whether err means no docker command can succeed
+// on the environment, so actions should give up on it rather than skip the
+// item that failed.
+func environmentDown(err error) bool {
+	return errors.Is(err, ErrHostUnreachable) || errors.Is(err, ErrDaemonNotRunning)
+}
+
+// daemonFailure reports whether a docker command failed to reach the
+// daemon, locally or over ssh, and docker's explanation.
+func daemonFailure(err error) (string, bool) {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return "", false
+	}
+	for _, line := range strings.Split(string(exitErr.Stderr), "\n") {
+		if line = strings.TrimSpace(line); strings.Contains(line, daemonNotRunningMessage) {
+			return line, true
+		}
+	}
+	return "", false
+}
+
+// sshConnectionFailure reports whether cmd is an ssh command that failed to
+// connect or lost its connection, and ssh's explanation. ssh exits with 255
+// on its own errors; docker doesn't use that status.
+func sshConnectionFailure(cmd *exec.Cmd, err error) (string, bool) {
+	var exitErr *exec.ExitError
+	if len(cmd.Args) == 0 || cmd.Args[0] != "ssh" || !errors.As(err, &exitErr) || exitErr.ExitCode() != 255 {
+		return "", false
+	}
+	// Stderr is only kept when the caller didn't redirect it, or passed it
+	// to commandErrorWithStderr.
+	lines := strings.Split(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+	if reason := strings.TrimSpace(lines[len(lines)-1]); reason != "" {
+		return reason, true
+	}
+	return "ssh connection failed", true
+}
+
+// asActionError makes sure err is an *ActionError carrying env and action.
+func asActionError(env, action string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var actionErr *ActionError
+	if !errors.As(err, &actionErr) {
+		return &ActionError{Environment: env, Action: action, Err: err}
+	}
+	if actionErr.Environment == "" {
+		actionErr.Environment = env
+	}
+	if actionErr.Action == "" {
+		actionErr.Action = action
+	}
+	return err
+}
+
+// ParseError is returned when a line of docker's JSON output doesn't decode,
+// typically because a docker version changed the format. Line is the raw
+// line, for reporting format drift.
+type ParseError struct {
+	Line string
+	// Offset is the byte offset in Line where decoding failed, when known.
+	Offset int64
+	// Type names what the line was decoded into, e.g. "ContainerInfo".
+	Type string
+	Err  error
+}
+
+// maxParseErrorLine bounds how much of the line Error quotes.
+const maxParseErrorLine = 200
+
+func (e *ParseError) Error() string {
+	line := e.Line
+	if len(line) > maxParseErrorLine {
+		line = line[:maxParseErrorLine] + "…"
+	}
+	return fmt.Sprintf("parsing %s at offset %d: %v: %q", e.Type, e.Offset, e.Err, line)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// unmarshalLine decodes a line of JSON output into v, a pointer to the type
+// named typeName, failing with a *ParseError.
+func unmarshalLine(line, typeName string, v any) error {
+	err := json.Unmarshal([]byte(line), v)
+	if err == nil {
+		return nil
+	}
+	parseErr := &ParseError{Line: line, Type: typeName, Err: err}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		parseErr.Offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		parseErr.Offset = typeErr.Offset
+	}
+	return parseErr
+}
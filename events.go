@@ -0,0 +1,81 @@
+package dockermonitor
+
+import (
+	"bufio"
+	"context"
+	"time"
+)
+
+// EventInfo is one line of `docker events --format "{{json .}}"`.
+type EventInfo struct {
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	Actor  struct {
+		ID         string            `json:"id"`
+		Attributes map[string]string `json:"attributes,omitempty"`
+	} `json:"actor"`
+	Scope    string `json:"scope"`
+	Time     int64  `json:"time"`
+	TimeNano int64  `json:"timeNano"`
+}
+
+// Timestamp returns when the daemon recorded the event.
+func (e EventInfo) Timestamp() time.Time {
+	if e.TimeNano != 0 {
+		return time.Unix(0, e.TimeNano)
+	}
+	return time.Unix(e.Time, 0)
+}
+
+// StreamEvents runs `docker events` against env and sends every event to ch
+// until ctx is cancelled, which is the normal way to stop it and returns
+// ctx's error. A full channel never stalls the docker reader: the event is
+// dropped and counted in the environment's DroppedEvents instead.
+func (d *DockerMonitor) StreamEvents(ctx context.Context, env string, ch chan<- EventInfo) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	cmd, release := d.DockerCommand(ctx, env, "events", "--format", "{{json .}}")
+	defer release()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return commandError(cmd, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return commandError(cmd, err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	// Events carry every container label as an attribute, so lines can be long.
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event EventInfo
+		if err := unmarshalLine(scanner.Text(), "EventInfo", &event); err != nil {
+			d.logger().Warn("skipping malformed docker event", "environment", env, "error", err)
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+			d.UpdateEnvironment(env, func(e *DockerEnvironment) {
+				e.DroppedEvents++
+			})
+		}
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// docker would block on the unread pipe forever otherwise.
+		cmd.Process.Kill()
+	}
+
+	err = cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case scanErr != nil:
+		return scanErr
+	case err != nil:
+		return commandError(cmd, err)
+	}
+	return nil
+}
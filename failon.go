@@ -0,0 +1,136 @@
+package dockermonitor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// conditionMetrics are the per-environment values -fail-on conditions can test.
+var conditionMetrics = map[string]func(e DockerEnvironment) int{
+	"running":         func(e DockerEnvironment) int { return e.RunningContainers },
+	"stopped":         func(e DockerEnvironment) int { return e.StoppedContainers },
+	"images":          func(e DockerEnvironment) int { return e.TotalLocalDockerImages },
+	"unhealthy":       func(e DockerEnvironment) int { return len(e.UnhealthyContainers()) },
+	"unused-images":   func(e DockerEnvironment) int { return len(e.UnusedImages) },
+	"orphaned":        func(e DockerEnvironment) int { return len(e.OrphanedContainers) },
+	"outdated":        func(e DockerEnvironment) int { return len(e.OutdatedImages) },
+	"arch-mismatch":   func(e DockerEnvironment) int { return len(e.ArchMismatches) },
+	"port-mismatch":   func(e DockerEnvironment) int { return len(e.PortMismatches) },
+	"label-violation": func(e DockerEnvironment) int { return len(e.LabelViolations) },
+	"oversized-logs":  func(e DockerEnvironment) int { return len(e.OversizedLogs) },
+	"root":            func(e DockerEnvironment) int { return len(e.RootContainers) },
+	"writable-rootfs": func(e DockerEnvironment) int { return len(e.WritableRootfsContainers) },
+	"unconfined": func(e DockerEnvironment) int {
+		count := 0
+		for _, f := range e.SecurityFindings {
+			if f.Check == "seccomp-unconfined" || f.Check == "apparmor-unconfined" {
+				count += 1
+			}
+		}
+		return count
+	},
+	"no-memory-limit": func(e DockerEnvironment) int { return len(e.UnboundedMemory) },
+	"unbounded-tmpfs": func(e DockerEnvironment) int { return len(e.UnboundedTmpfs) },
+	"disk-writes":     func(e DockerEnvironment) int { return len(e.HeavyDiskWriters) },
+	"low-nofile":      func(e DockerEnvironment) int { return len(e.LowNofileContainers) },
+	"short-stop":      func(e DockerEnvironment) int { return len(e.ShortStopTimeouts) },
+	"start-skew":      func(e DockerEnvironment) int { return len(e.StartSkewedContainers) },
+	"shared-network":  func(e DockerEnvironment) int { return len(e.SharedNetworkContainers) },
+	"unmet-depends":   func(e DockerEnvironment) int { return len(e.UnmetDependencies) },
+	"log-driver":      func(e DockerEnvironment) int { return len(e.UnexpectedLogDrivers) },
+	"unreachable":     func(e DockerEnvironment) int { return len(e.UnreachableContainers) },
+	"flapping":        func(e DockerEnvironment) int { return len(e.Flapping) },
+	"zombies":         func(e DockerEnvironment) int { return len(e.ZombieContainers) },
+	"stale-tag":       func(e DockerEnvironment) int { return len(e.StaleTagContainers) },
+	"unsigned":        func(e DockerEnvironment) int { return len(e.UnsignedProductionImages) },
+	"no-provenance":   func(e DockerEnvironment) int { return len(e.MissingProvenance) },
+	"dangling-volume": func(e DockerEnvironment) int { return len(e.DanglingVolumes) },
+	"config-drift": func(e DockerEnvironment) int {
+		return countContainers(e, func(c ContainerInfo) bool { return c.ConfigDrift })
+	},
+}
+
+// comparisons are checked longest first so ">=" isn't read as ">".
+var comparisons = []struct {
+	op      string
+	compare func(a, b int) bool
+}{
+	{">=", func(a, b int) bool { return a >= b }},
+	{"<=", func(a, b int) bool { return a <= b }},
+	{"!=", func(a, b int) bool { return a != b }},
+	{"==", func(a, b int) bool { return a == b }},
+	{">", func(a, b int) bool { return a > b }},
+	{"<", func(a, b int) bool { return a < b }},
+	{"=", func(a, b int) bool { return a == b }},
+}
+
+// FailCondition is a parsed -fail-on expression such as "unhealthy" or
+// "stopped>5". A bare metric name means "metric>0".
+type FailCondition struct {
+	Expr      string
+	metric    string
+	threshold int
+	compare   func(a, b int) bool
+}
+
+// ParseFailCondition parses expr into a condition.
+func ParseFailCondition(expr string) (FailCondition, error) {
+	condition := FailCondition{Expr: expr, metric: strings.TrimSpace(expr), compare: func(a, b int) bool { return a > b }}
+	for _, c := range comparisons {
+		metric, value, found := strings.Cut(expr, c.op)
+		if !found {
+			continue
+		}
+		threshold, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return FailCondition{}, fmt.Errorf("fail-on %q: invalid threshold %q", expr, value)
+		}
+		condition.metric, condition.threshold, condition.compare = strings.TrimSpace(metric), threshold, c.compare
+		break
+	}
+	if _, known := conditionMetrics[condition.metric]; !known {
+		return FailCondition{}, fmt.Errorf("fail-on %q: unknown metric %q, valid metrics are %s",
+			expr, condition.metric, strings.Join(sortedKeys(conditionMetrics), ", "))
+	}
+	return condition, nil
+}
+
+// Evaluate returns one message per environment where the condition holds.
+func (c FailCondition) Evaluate(d *DockerMonitor) []string {
+	var violations []string
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		if value, holds := c.holds(dockerEnv); holds {
+			violations = append(violations, fmt.Sprintf("%s: %s=%d (fail-on %s)", dockerEnv.Environment, c.metric, value, c.Expr))
+		}
+	}
+	return violations
+}
+
+// holds returns the condition's metric for e and whether the condition holds.
+func (c FailCondition) holds(e DockerEnvironment) (int, bool) {
+	value := conditionMetrics[c.metric](e)
+	return value, c.compare(value, c.threshold)
+}
+
+// EvaluateFailConditions checks every condition and returns all violations,
+// sorted; the run passes only when the result is empty.
+func EvaluateFailConditions(d *DockerMonitor, conditions []FailCondition) []string {
+	var violations []string
+	for _, c := range conditions {
+		violations = append(violations, c.Evaluate(d)...)
+	}
+	sort.Strings(violations)
+	return violations
+}
+
+func countContainers(e DockerEnvironment, match func(c ContainerInfo) bool) int {
+	count := 0
+	for _, cont := range e.ContainersInfo {
+		if match(cont) {
+			count += 1
+		}
+	}
+	return count
+}
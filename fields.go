@@ -0,0 +1,96 @@
+package dockermonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// dockerPsFields are the ContainerInfo fields that come straight from `docker ps`.
+var dockerPsFields = []string{
+	"command", "createdAt", "id", "image", "labels", "localVolumes", "mounts",
+	"names", "networks", "ports", "runningFor", "size", "state", "status",
+}
+
+// defaultTableFields keeps the table readable in a terminal.
+var defaultTableFields = []string{"id", "names", "image", "state", "status"}
+
+// containerFieldIndex maps ContainerInfo json names to struct field indexes.
+var containerFieldIndex = func() map[string]int {
+	index := make(map[string]int)
+	t := reflect.TypeOf(ContainerInfo{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			index[name] = i
+		}
+	}
+	return index
+}()
+
+// ContainerFieldNames lists the field names accepted by FormatOptions.Fields.
+func ContainerFieldNames() []string {
+	return sortedKeys(containerFieldIndex)
+}
+
+// validateContainerFields rejects field names ContainerInfo doesn't have.
+func validateContainerFields(fields []string) error {
+	for _, field := range fields {
+		if _, found := containerFieldIndex[field]; !found {
+			return fmt.Errorf("unknown field %q, valid fields are %s", field, strings.Join(ContainerFieldNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// containerFieldStrings renders the selected fields of c for text outputs.
+// Structured fields are written as compact JSON.
+func containerFieldStrings(c ContainerInfo, fields []string) []string {
+	v := reflect.ValueOf(c)
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		value := v.Field(containerFieldIndex[field])
+		switch value.Kind() {
+		case reflect.String:
+			values[i] = value.String()
+		case reflect.Slice, reflect.Map, reflect.Pointer, reflect.Struct:
+			if value.IsZero() {
+				continue
+			}
+			data, _ := json.Marshal(value.Interface())
+			values[i] = string(data)
+		default:
+			values[i] = fmt.Sprint(value.Interface())
+		}
+	}
+	return values
+}
+
+// withSelectedFields prepares an environment for JSON output: containers only
+// carry fields and struct field names are spelled in case k. With no fields
+// and the default case the environment is returned as is.
+func withSelectedFields(e DockerEnvironment, fields []string, k KeyCase) any {
+	if len(fields) == 0 && k == KeyCaseDefault {
+		return e
+	}
+	object := recaseStruct(reflect.ValueOf(e), k, nil)
+	if len(fields) == 0 {
+		return object
+	}
+	containers := make([]casedObject, 0, len(e.ContainersInfo))
+	for _, cont := range e.ContainersInfo {
+		containers = append(containers, selectedContainerFields(cont, fields, k, nil))
+	}
+	return object.set(k.apply("ContainersInfo"), containers)
+}
+
+// selectedContainerFields appends the selected fields of c, in the order
+// given, to object.
+func selectedContainerFields(c ContainerInfo, fields []string, k KeyCase, object casedObject) casedObject {
+	v := reflect.ValueOf(c)
+	for _, field := range fields {
+		object = append(object, casedField{k.apply(field), recase(v.Field(containerFieldIndex[field]), k)})
+	}
+	return object
+}
@@ -0,0 +1,13 @@
+module github.com/adrien19/dockermonitor
+
+go 1.22
+
+require github.com/gorilla/websocket v1.5.3
+
+require gopkg.in/yaml.v3 v3.0.1
+
+require golang.org/x/sync v0.10.0
+
+require github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+
+require gopkg.in/natefinch/lumberjack.v2 v2.2.1
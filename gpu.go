@@ -0,0 +1,106 @@
+package dockermonitor
+
+import (
+	"context"
+	"slices"
+)
+
+// AllGPUs is the ContainerInfo.GPUs value of containers started with
+// `--gpus all`, whose device count only the host knows.
+const AllGPUs = -1
+
+// deviceRequest is one entry of HostConfig.DeviceRequests.
+type deviceRequest struct {
+	Driver       string
+	Count        int
+	DeviceIDs    []string
+	Capabilities [][]string
+}
+
+// gpus returns how many GPUs the request allocates, AllGPUs for all of them,
+// or zero when it isn't a GPU request.
+func (r deviceRequest) gpus() int {
+	isGPU := r.Driver == "nvidia"
+	for _, capabilities := range r.Capabilities {
+		isGPU = isGPU || slices.Contains(capabilities, "gpu")
+	}
+	switch {
+	case !isGPU:
+		return 0
+	case len(r.DeviceIDs) > 0:
+		return len(r.DeviceIDs)
+	case r.Count < 0:
+		return AllGPUs
+	}
+	return r.Count
+}
+
+type CheckGPUContainers struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckGPUContainers) Name() string        { return "gpu-containers" }
+func (c CheckGPUContainers) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records the GPUs allocated to every container and sums up the ones
+// held by running containers.
+func (c CheckGPUContainers) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	// The whole HostConfig is requested since daemons before API 1.40 have no
+	// DeviceRequests field and would fail the template.
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .HostConfig}}")
+	if err != nil {
+		return err
+	}
+
+	gpusByID := make(map[string]int)
+	var gpuContainers []string
+	inUse := 0
+	for _, cont := range dockerEnv.ContainersInfo {
+		raw, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		var hostConfig struct {
+			DeviceRequests []deviceRequest
+		}
+		if err := unmarshalLine(raw, "HostConfig", &hostConfig); err != nil {
+			return err
+		}
+
+		gpus := 0
+		for _, request := range hostConfig.DeviceRequests {
+			n := request.gpus()
+			if n == AllGPUs || gpus == AllGPUs {
+				gpus = AllGPUs
+			} else {
+				gpus += n
+			}
+		}
+		if gpus == 0 {
+			continue
+		}
+		gpusByID[cont.ID] = gpus
+		if cont.State == "running" {
+			gpuContainers = append(gpuContainers, cont.Names)
+			if gpus > 0 {
+				inUse += gpus
+			}
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].GPUs = gpusByID[e.ContainersInfo[i].ID]
+		}
+		e.GPUContainers = gpuContainers
+		e.GPUsInUse = inUse
+	})
+	c.dockerMonitor.logger().Info("gpu containers", "environment", env, "containers", len(gpuContainers), "gpusInUse", inUse)
+	return nil
+}
@@ -0,0 +1,116 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of CheckContainerHealthHistory.
+const (
+	DefaultFlapWindow      = 10 * time.Minute
+	DefaultFlapTransitions = 3
+)
+
+// CheckContainerHealthHistory remembers each container's health across runs,
+// which only makes sense when the same action runs repeatedly, as with the
+// serve and alert commands, and flags containers that flap: their health
+// check switched between healthy and unhealthy at least Transitions times
+// within Window. A container that is steadily unhealthy isn't flapping.
+type CheckContainerHealthHistory struct {
+	dockerMonitor *DockerMonitor
+	// Window is how far back health is remembered; DefaultFlapWindow when zero.
+	Window time.Duration
+	// Transitions is how many changes within Window count as flapping;
+	// DefaultFlapTransitions when zero.
+	Transitions int
+	history     *healthHistory
+}
+
+type healthSample struct {
+	at      time.Time
+	healthy bool
+}
+
+// healthHistory holds the samples of all environments, whose workflows may
+// run concurrently, keyed by environment and container ID.
+type healthHistory struct {
+	mu      sync.Mutex
+	samples map[[2]string][]healthSample
+}
+
+func (c CheckContainerHealthHistory) Name() string        { return "container-health-history" }
+func (c CheckContainerHealthHistory) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerHealthHistory) Execute(ctx context.Context, env string) error {
+	window := c.Window
+	if window <= 0 {
+		window = DefaultFlapWindow
+	}
+	transitions := c.Transitions
+	if transitions <= 0 {
+		transitions = DefaultFlapTransitions
+	}
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	// Samples are taken at the time the containers were listed, so running
+	// the action again on the same listing adds nothing.
+	now := dockerEnv.CollectedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	h := c.history
+	h.mu.Lock()
+	current := make(map[[2]string]bool)
+	var flapping []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		key := [2]string{env, cont.ID}
+		current[key] = true
+		samples := h.samples[key]
+		// Containers that are starting or have no health check add no sample.
+		if healthy, known := containerHealth(cont); known && (len(samples) == 0 || samples[len(samples)-1].at.Before(now)) {
+			samples = append(samples, healthSample{at: now, healthy: healthy})
+		}
+		for len(samples) > 0 && now.Sub(samples[0].at) > window {
+			samples = samples[1:]
+		}
+		h.samples[key] = samples
+
+		changes := 0
+		for i := 1; i < len(samples); i++ {
+			if samples[i].healthy != samples[i-1].healthy {
+				changes++
+			}
+		}
+		if changes >= transitions {
+			flapping = append(flapping, cont.Names)
+		}
+	}
+	// Forget removed containers.
+	for key := range h.samples {
+		if key[0] == env && !current[key] {
+			delete(h.samples, key)
+		}
+	}
+	h.mu.Unlock()
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.Flapping = flapping
+	})
+	c.dockerMonitor.logger().Info("container health history", "environment", env, "flapping", len(flapping))
+	return nil
+}
+
+// containerHealth reads the health check result docker ps shows in Status,
+// e.g. "Up 2 hours (healthy)". known is false while the check is starting
+// and for containers without one.
+func containerHealth(cont ContainerInfo) (healthy, known bool) {
+	switch {
+	case strings.Contains(cont.Status, "(healthy)"):
+		return true, true
+	case strings.Contains(cont.Status, "(unhealthy)"):
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,116 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// HostResources describes the machine the daemon runs on, so container
+// behaviour can be correlated with a host that is short of memory or disk.
+// The available memory and disk figures are 0 when the host can't be
+// reached outside of docker, e.g. for a daemon behind a TCP DOCKER_HOST.
+type HostResources struct {
+	CPUs                 int    `json:"cpus"`
+	MemoryTotalBytes     int64  `json:"memoryTotalBytes"`
+	MemoryAvailableBytes int64  `json:"memoryAvailableBytes"`
+	DockerRootDir        string `json:"dockerRootDir"`
+	DiskTotalBytes       int64  `json:"diskTotalBytes"`
+	DiskFreeBytes        int64  `json:"diskFreeBytes"`
+}
+
+type CheckHostResources struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckHostResources) Name() string { return "host-resources" }
+
+// Execute takes the CPU count, total memory and docker root from `docker
+// info`, and the available memory and the free space on the docker root from
+// /proc/meminfo and df on the host itself, over SSH for remote environments.
+func (c CheckHostResources) Execute(ctx context.Context, env string) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "info", "--format", "{{json .}}")
+	if err != nil {
+		return err
+	}
+	var info struct {
+		NCPU          int
+		MemTotal      int64
+		DockerRootDir string
+	}
+	if err := json.Unmarshal(out, &info); err != nil {
+		return err
+	}
+	resources := HostResources{CPUs: info.NCPU, MemoryTotalBytes: info.MemTotal, DockerRootDir: info.DockerRootDir}
+
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	if dockerEnv.Host != "" || (runtime.GOOS == "linux" && c.dockerMonitor.runsLocally(env)) {
+		if err := c.readHost(ctx, env, &resources); err != nil {
+			// Typically a host that isn't Linux; the docker info figures still hold.
+			c.dockerMonitor.logger().Warn("reading host resources", "environment", env, "error", err)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.HostResources = &resources
+	})
+	c.dockerMonitor.logger().Info("host resources", "environment", env, "cpus", resources.CPUs,
+		"memoryAvailableBytes", resources.MemoryAvailableBytes, "diskFreeBytes", resources.DiskFreeBytes)
+	return nil
+}
+
+// readHost fills in the available memory and disk space of env's host.
+func (c CheckHostResources) readHost(ctx context.Context, env string, resources *HostResources) error {
+	cmd, release := c.dockerMonitor.hostCommand(ctx, env, "cat", "/proc/meminfo")
+	meminfo, err := cmd.Output()
+	release()
+	if err != nil {
+		return commandError(cmd, err)
+	}
+	if resources.MemoryAvailableBytes, err = meminfoBytes(string(meminfo), "MemAvailable"); err != nil {
+		return err
+	}
+	if resources.DockerRootDir == "" {
+		return nil
+	}
+	// -P keeps each filesystem on one line, -k makes the sizes KiB.
+	cmd, release = c.dockerMonitor.hostCommand(ctx, env, "df", "-Pk", resources.DockerRootDir)
+	df, err := cmd.Output()
+	release()
+	if err != nil {
+		return commandError(cmd, err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(df)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(lines) < 2 || len(fields) < 4 {
+		return fmt.Errorf("unexpected df output %q", df)
+	}
+	total, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	free, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return err
+	}
+	resources.DiskTotalBytes, resources.DiskFreeBytes = total*1024, free*1024
+	return nil
+}
+
+// meminfoBytes returns the value of key in /proc/meminfo contents in bytes.
+func meminfoBytes(meminfo, key string) (int64, error) {
+	for _, line := range strings.Split(meminfo, "\n") {
+		if value, found := strings.CutPrefix(line, key+":"); found {
+			kb, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "kB")), 10, 64)
+			return kb * 1024, err
+		}
+	}
+	return 0, errors.New("no " + key + " in meminfo")
+}
@@ -0,0 +1,61 @@
+package dockermonitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteInfluxLineProtocol writes the gauges of WritePrometheusText as InfluxDB
+// line protocol, one line per measurement and environment, e.g.
+//
+//	docker_containers,environment=Dev running=3i,stopped=1i 1704103200000000000
+//
+// Timestamps are each environment's CollectedAt in nanoseconds, or the
+// current time for environments that weren't collected.
+func (d *DockerMonitor) WriteInfluxLineProtocol(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		collectedAt := dockerEnv.CollectedAt
+		if collectedAt.IsZero() {
+			collectedAt = time.Now()
+		}
+		timestamp := collectedAt.UnixNano()
+		tags := "environment=" + escapeInfluxTag(dockerEnv.Environment)
+
+		// Metrics sharing a measurement become fields of the same line.
+		var measurements []string
+		fields := make(map[string][]string)
+		for _, m := range environmentMetrics {
+			if _, found := fields[m.Measurement]; !found {
+				measurements = append(measurements, m.Measurement)
+			}
+			fields[m.Measurement] = append(fields[m.Measurement], fmt.Sprintf("%s=%di", m.Field, int64(m.Value(dockerEnv))))
+		}
+		for _, measurement := range measurements {
+			fmt.Fprintf(bw, "%s,%s %s %d\n", measurement, tags, strings.Join(fields[measurement], ","), timestamp)
+		}
+
+		perImage := dockerEnv.ContainersPerImage()
+		for _, image := range sortedKeys(perImage) {
+			fmt.Fprintf(bw, "%s,%s,image=%s running=%di %d\n", containersPerImageMetric, tags, escapeInfluxTag(image), perImage[image], timestamp)
+		}
+	}
+	return bw.Flush()
+}
+
+var influxTagEscaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `, "\n", `\n`)
+
+// escapeInfluxTag escapes a tag value for line protocol.
+func escapeInfluxTag(v string) string {
+	return influxTagEscaper.Replace(v)
+}
+
+// InfluxFormatter adapts WriteInfluxLineProtocol to the Formatter interface.
+type InfluxFormatter struct{}
+
+func (f InfluxFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	return d.WriteInfluxLineProtocol(w)
+}
@@ -0,0 +1,131 @@
+package dockermonitor
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// inspect runs a single batched `docker inspect` for all ids and returns the
+// rendered format per requested id. The object ID is prepended to the format
+// so output lines can be matched back to short IDs from `docker ps`/`docker images`.
+// Objects that disappeared between listing and inspecting are left out.
+func (d *DockerMonitor) inspect(ctx context.Context, env string, ids []string, format string) (map[string]string, error) {
+	results := make(map[string]string)
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	args := append([]string{"inspect", "--format", "{{.Id}} " + format}, ids...)
+	out, err := d.DockerOutput(ctx, env, args...)
+	// docker inspect exits non-zero when any single object is missing, but still
+	// prints the ones it found, so only fail when nothing came back.
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fullID, value, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		fullID = strings.TrimPrefix(fullID, "sha256:")
+		for _, id := range ids {
+			if strings.HasPrefix(fullID, strings.TrimPrefix(id, "sha256:")) {
+				results[id] = value
+			}
+		}
+	}
+	return results, nil
+}
+
+// ContainerNetwork holds a container's addresses on a single network.
+// Containers using host networking report a "host" entry without addresses.
+type ContainerNetwork struct {
+	Network     string `json:"network"`
+	IPAddress   string `json:"ipAddress"`
+	IPv6Address string `json:"ipv6Address"`
+	Gateway     string `json:"gateway"`
+	MacAddress  string `json:"macAddress"`
+}
+
+type CheckContainerIPs struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerIPs) Name() string        { return "container-ips" }
+func (c CheckContainerIPs) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerIPs) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .NetworkSettings.Networks}}")
+	if err != nil {
+		return err
+	}
+
+	networksByID := make(map[string][]ContainerNetwork)
+	withAddress := 0
+	for _, cont := range dockerEnv.ContainersInfo {
+		networks, err := parseContainerNetworks(inspected[cont.ID])
+		if err != nil {
+			return err
+		}
+		for _, network := range networks {
+			if network.IPAddress != "" || network.IPv6Address != "" {
+				withAddress += 1
+				break
+			}
+		}
+		networksByID[cont.ID] = networks
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			if networks, ok := networksByID[e.ContainersInfo[i].ID]; ok {
+				e.ContainersInfo[i].NetworkAddresses = networks
+			}
+		}
+	})
+	c.dockerMonitor.logger().Info("container networks", "environment", env, "withAddress", withAddress)
+	return nil
+}
+
+// parseContainerNetworks decodes the NetworkSettings.Networks inspect JSON into
+// a slice sorted by network name.
+func parseContainerNetworks(raw string) ([]ContainerNetwork, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "null" {
+		return nil, nil
+	}
+	var settings map[string]struct {
+		IPAddress         string
+		GlobalIPv6Address string
+		Gateway           string
+		MacAddress        string
+	}
+	if err := unmarshalLine(raw, "NetworkSettings", &settings); err != nil {
+		return nil, err
+	}
+
+	var networks []ContainerNetwork
+	for name, s := range settings {
+		networks = append(networks, ContainerNetwork{
+			Network:     name,
+			IPAddress:   s.IPAddress,
+			IPv6Address: s.GlobalIPv6Address,
+			Gateway:     s.Gateway,
+			MacAddress:  s.MacAddress,
+		})
+	}
+	sort.Slice(networks, func(i, j int) bool {
+		return networks[i].Network < networks[j].Network
+	})
+	return networks, nil
+}
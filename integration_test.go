@@ -0,0 +1,66 @@
+//go:build integration
+
+package dockermonitor_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adrien19/dockermonitor"
+)
+
+// TestIntegrationContainersStatus runs CheckContainersStatus against the
+// local docker daemon, with a sleeping busybox container to find. Run it
+// with `go test -tags integration`; it is skipped without a daemon.
+func TestIntegrationContainersStatus(t *testing.T) {
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		t.Skipf("docker isn't available: %v", err)
+	}
+
+	name := fmt.Sprintf("dockermonitor-integration-%d", time.Now().UnixNano())
+	out, err := exec.Command("docker", "run", "-d", "--name", name, "--label", "dockermonitor.test=integration",
+		"busybox", "sleep", "300").CombinedOutput()
+	if err != nil {
+		t.Fatalf("starting busybox: %v: %s", err, out)
+	}
+	id := strings.TrimSpace(string(out))
+	t.Cleanup(func() {
+		if out, err := exec.Command("docker", "rm", "-f", id).CombinedOutput(); err != nil {
+			t.Logf("removing %s: %v: %s", name, err, out)
+		}
+	})
+
+	d := dockermonitor.NewDockerMonitor([]string{"local"})
+	w := &dockermonitor.Workflow{
+		Name:    "local",
+		Actions: []dockermonitor.Action{d.CallContainersStatus()},
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := w.ExecuteActions(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerEnv, _ := d.Environment("local")
+	for _, cont := range dockerEnv.ContainersInfo {
+		if cont.Names != name {
+			continue
+		}
+		if !strings.HasPrefix(id, cont.ID) || cont.State != "running" || cont.Image != "busybox" ||
+			!strings.Contains(cont.Labels, "dockermonitor.test=integration") {
+			t.Errorf("container = %+v, want running busybox %s", cont, id)
+		}
+		if dockerEnv.RunningContainers < 1 {
+			t.Errorf("RunningContainers = %d, want at least 1", dockerEnv.RunningContainers)
+		}
+		return
+	}
+	t.Errorf("%s not among the %d collected containers", name, len(dockerEnv.ContainersInfo))
+}
@@ -0,0 +1,223 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// KeyCase selects how JSON output spells struct field names. Map keys, such
+// as label names or extension names, are data and are never renamed.
+type KeyCase string
+
+const (
+	// KeyCaseDefault keeps the names from the json tags: camelCase for
+	// containers and images, Go field names for environments.
+	KeyCaseDefault KeyCase = ""
+	KeyCaseCamel   KeyCase = "camel"
+	KeyCaseSnake   KeyCase = "snake"
+	KeyCasePascal  KeyCase = "pascal"
+)
+
+// ParseKeyCase validates a -key-case style value.
+func ParseKeyCase(s string) (KeyCase, error) {
+	switch k := KeyCase(s); k {
+	case KeyCaseDefault, KeyCaseCamel, KeyCaseSnake, KeyCasePascal:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown key case %q, valid cases are camel, snake and pascal", s)
+}
+
+// apply renames a json field name, e.g. "createdAt" becomes "created_at"
+// in snake case and "CreatedAt" in pascal case.
+func (k KeyCase) apply(name string) string {
+	if k == KeyCaseDefault {
+		return name
+	}
+	words := splitWords(name)
+	for i, word := range words {
+		switch {
+		case k == KeyCaseSnake || (k == KeyCaseCamel && i == 0):
+			words[i] = strings.ToLower(word)
+		default:
+			words[i] = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+		}
+	}
+	if k == KeyCaseSnake {
+		return strings.Join(words, "_")
+	}
+	return strings.Join(words, "")
+}
+
+// splitWords splits camelCase, PascalCase and snake_case names into words,
+// keeping acronyms together: "APIVersion" is "API" and "Version".
+func splitWords(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '_' || r == '-' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(r) {
+			continue
+		}
+		prev := runes[i-1]
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// casedField is one member of a casedObject.
+type casedField struct {
+	key   string
+	value any
+}
+
+// casedObject is a JSON object that keeps its fields in struct order.
+type casedObject []casedField
+
+func (o casedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// set replaces the value under key, or appends it.
+func (o casedObject) set(key string, value any) casedObject {
+	for i := range o {
+		if o[i].key == key {
+			o[i].value = value
+			return o
+		}
+	}
+	return append(o, casedField{key, value})
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// withKeyCase converts v into values that marshal like v does, except that
+// struct field names are spelled in case k. It follows encoding/json for
+// tags, omitempty and embedded structs.
+func withKeyCase(v any, k KeyCase) any {
+	return recase(reflect.ValueOf(v), k)
+}
+
+func recase(v reflect.Value, k KeyCase) any {
+	if !v.IsValid() {
+		return nil
+	}
+	// Types with their own encoding, such as time.Time or json.RawMessage,
+	// are left to it.
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return recase(v.Elem(), k)
+	case reflect.Struct:
+		return recaseStruct(v, k, nil)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = recase(iter.Value(), k)
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && (v.IsNil() || v.Type().Elem().Kind() == reflect.Uint8) {
+			return v.Interface()
+		}
+		s := make([]any, v.Len())
+		for i := range s {
+			s[i] = recase(v.Index(i), k)
+		}
+		return s
+	}
+	return v.Interface()
+}
+
+func recaseStruct(v reflect.Value, k KeyCase, object casedObject) casedObject {
+	if object == nil {
+		object = casedObject{}
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		value := v.Field(i)
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			object = recaseStruct(value, k, object)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(value) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		object = object.set(k.apply(name), recase(value, k))
+	}
+	return object
+}
+
+// isEmptyValue is encoding/json's definition of empty for omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
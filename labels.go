@@ -0,0 +1,103 @@
+package dockermonitor
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// sortedLabels returns a docker ps Labels column with the labels sorted by
+// key. docker lists them in random order, which would make snapshots of
+// unchanged containers differ.
+func sortedLabels(labels string) string {
+	if !strings.Contains(labels, ",") {
+		return labels
+	}
+	m := ContainerInfo{Labels: labels}.LabelMap()
+	pieces := make([]string, 0, len(m))
+	for _, key := range sortedKeys(m) {
+		pieces = append(pieces, key+"="+m[key])
+	}
+	return strings.Join(pieces, ",")
+}
+
+// LabelMap parses the comma-separated "key=value" Labels column of docker ps.
+// docker doesn't escape commas inside values, so a piece without "=" is taken
+// to continue the previous value.
+func (c ContainerInfo) LabelMap() map[string]string {
+	labels := make(map[string]string)
+	last := ""
+	for _, piece := range strings.Split(c.Labels, ",") {
+		key, value, found := strings.Cut(piece, "=")
+		switch {
+		case found:
+			labels[key] = value
+			last = key
+		case last != "":
+			labels[last] += "," + piece
+		case piece != "":
+			labels[piece] = ""
+		}
+	}
+	return labels
+}
+
+// LabelPolicy lists the labels containers must carry.
+type LabelPolicy struct {
+	Required []string `json:"required,omitempty"`
+	// Allowed maps a label to its permitted values; containers with the label
+	// set to any other value are flagged. The label itself stays optional
+	// unless it is also Required.
+	Allowed map[string][]string `json:"allowed,omitempty"`
+}
+
+// LabelViolation reports how one container breaks the label policy.
+type LabelViolation struct {
+	Container string   `json:"container"`
+	Missing   []string `json:"missing,omitempty"`
+	// Disallowed holds "label=value" pairs whose value isn't allowed.
+	Disallowed []string `json:"disallowed,omitempty"`
+}
+
+type CheckContainerLabelsPolicy struct {
+	dockerMonitor *DockerMonitor
+	Policy        LabelPolicy
+}
+
+func (c CheckContainerLabelsPolicy) Name() string        { return "container-labels-policy" }
+func (c CheckContainerLabelsPolicy) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerLabelsPolicy) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var violations []LabelViolation
+	for _, cont := range dockerEnv.ContainersInfo {
+		if violation, violated := c.Policy.check(cont); violated {
+			violations = append(violations, violation)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.LabelViolations = violations
+	})
+	c.dockerMonitor.logger().Info("container labels", "environment", env, "violations", len(violations))
+	return nil
+}
+
+func (p LabelPolicy) check(cont ContainerInfo) (LabelViolation, bool) {
+	violation := LabelViolation{Container: cont.Names}
+	labels := cont.LabelMap()
+	for _, label := range p.Required {
+		if _, found := labels[label]; !found {
+			violation.Missing = append(violation.Missing, label)
+		}
+	}
+	for label, allowed := range p.Allowed {
+		if value, found := labels[label]; found && !slices.Contains(allowed, value) {
+			violation.Disallowed = append(violation.Disallowed, label+"="+value)
+		}
+	}
+	sort.Strings(violation.Disallowed)
+	return violation, len(violation.Missing) > 0 || len(violation.Disallowed) > 0
+}
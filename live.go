@@ -0,0 +1,115 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// LiveUpdate is a message sent to WebSocket clients. The first one has Type
+// "snapshot" and every environment; later "update" messages only carry the
+// environments that changed since the previous message.
+type LiveUpdate struct {
+	Type         string            `json:"type"`
+	SentAt       time.Time         `json:"sentAt"`
+	Environments []json.RawMessage `json:"environments"`
+}
+
+const liveWriteTimeout = 10 * time.Second
+
+// The default origin check only lets pages served from the same host connect.
+var upgrader = websocket.Upgrader{}
+
+// Notify tells connected WebSocket clients that the monitor's data changed.
+// POST /refresh calls it; code updating the monitor on its own schedule
+// should too.
+func (s *Server) Notify() {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for updates := range s.clients {
+		// A pending notification already covers this one.
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (s *Server) subscribe() chan struct{} {
+	updates := make(chan struct{}, 1)
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if s.clients == nil {
+		s.clients = make(map[chan struct{}]struct{})
+	}
+	s.clients[updates] = struct{}{}
+	return updates
+}
+
+func (s *Server) unsubscribe(updates chan struct{}) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	delete(s.clients, updates)
+}
+
+func (s *Server) streamUpdates(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already replied with an error status.
+		return
+	}
+	defer conn.Close()
+
+	updates := s.subscribe()
+	defer s.unsubscribe(updates)
+
+	// Clients only listen, but reading is what processes pings and notices
+	// the connection closing.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	sent := make(map[string][]byte)
+	send := func(kind string) error {
+		update := LiveUpdate{Type: kind, SentAt: time.Now().UTC(), Environments: []json.RawMessage{}}
+		for _, dockerEnv := range s.monitor.Snapshot().DockerEnvironments {
+			data, err := json.Marshal(dockerEnv)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(sent[dockerEnv.Environment], data) {
+				continue
+			}
+			sent[dockerEnv.Environment] = data
+			update.Environments = append(update.Environments, data)
+		}
+		if kind == "update" && len(update.Environments) == 0 {
+			return nil
+		}
+		conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+		return conn.WriteJSON(update)
+	}
+
+	if err := send("snapshot"); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-closed:
+			return
+		case <-updates:
+			if err := send("update"); err != nil {
+				return
+			}
+		}
+	}
+}
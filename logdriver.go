@@ -0,0 +1,67 @@
+package dockermonitor
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+type CheckContainerLogDriver struct {
+	dockerMonitor *DockerMonitor
+	// Expected are the logging drivers containers may use, e.g. "fluentd";
+	// when empty, the daemon's default driver from CheckDaemonConfig.
+	Expected []string
+}
+
+func (c CheckContainerLogDriver) Name() string { return "container-log-driver" }
+func (c CheckContainerLogDriver) DependsOn() []string {
+	if len(c.Expected) == 0 {
+		return []string{"containers-status", "daemon-config"}
+	}
+	return []string{"containers-status"}
+}
+
+// Execute records each container's logging driver, counts how many
+// containers use each one and lists those on a driver other than the
+// expected ones, whose logs typically never reach the aggregation system.
+func (c CheckContainerLogDriver) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.HostConfig.LogConfig.Type}}")
+	if err != nil {
+		return err
+	}
+
+	expected := c.Expected
+	if len(expected) == 0 && dockerEnv.Daemon != nil && dockerEnv.Daemon.LoggingDriver != "" {
+		expected = []string{dockerEnv.Daemon.LoggingDriver}
+	}
+	drivers := make(map[string]string)
+	usage := make(map[string]int)
+	var unexpected []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		driver := strings.TrimSpace(inspected[cont.ID])
+		if driver == "" {
+			continue
+		}
+		drivers[cont.ID] = driver
+		usage[driver]++
+		if len(expected) > 0 && !slices.Contains(expected, driver) {
+			unexpected = append(unexpected, cont.Names)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].LogDriver = drivers[e.ContainersInfo[i].ID]
+		}
+		e.LogDrivers = usage
+		e.UnexpectedLogDrivers = unexpected
+	})
+	c.dockerMonitor.logger().Info("container log drivers", "environment", env, "drivers", usage, "unexpected", len(unexpected))
+	return nil
+}
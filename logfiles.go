@@ -0,0 +1,73 @@
+package dockermonitor
+
+import (
+	"context"
+	"os"
+)
+
+const DefaultLogFileThreshold = 100 * 1024 * 1024
+
+type CheckLogFileSizes struct {
+	dockerMonitor *DockerMonitor
+	// Threshold is the log file size in bytes above which a container is
+	// flagged; DefaultLogFileThreshold when zero.
+	Threshold int64
+}
+
+func (c CheckLogFileSizes) Name() string        { return "log-file-sizes" }
+func (c CheckLogFileSizes) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute stats each container's json-file log on the docker host, so it only
+// works for environments whose daemon runs on this machine, see runsLocally.
+// Containers using other logging drivers have no log path and are ignored.
+func (c CheckLogFileSizes) Execute(ctx context.Context, env string) error {
+	if !c.dockerMonitor.runsLocally(env) {
+		return unsupported("log files can only be read when the daemon runs on this machine")
+	}
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	threshold := c.Threshold
+	if threshold <= 0 {
+		threshold = DefaultLogFileThreshold
+	}
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.LogPath}}")
+	if err != nil {
+		return err
+	}
+
+	sizes := make(map[string]int64)
+	var total int64
+	var oversized []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		logPath := inspected[cont.ID]
+		if logPath == "" {
+			continue
+		}
+		info, err := os.Stat(logPath)
+		if err != nil {
+			// Usually missing permissions on the docker root or a daemon
+			// running inside a VM; keep going with the other containers.
+			c.dockerMonitor.logger().Warn("reading log file size", "environment", env, "container", cont.Names, "error", err)
+			continue
+		}
+		sizes[cont.ID] = info.Size()
+		total += info.Size()
+		if info.Size() > threshold {
+			oversized = append(oversized, cont.Names)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].LogFileSize = sizes[e.ContainersInfo[i].ID]
+		}
+		e.TotalLogFileBytes = total
+		e.OversizedLogs = oversized
+	})
+	c.dockerMonitor.logger().Info("log file sizes", "environment", env, "totalBytes", total, "oversized", len(oversized))
+	return nil
+}
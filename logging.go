@@ -0,0 +1,37 @@
+package dockermonitor
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logger returns the logger actions report progress on. Quiet raises the
+// level to errors only so scripts just see failures and the requested output.
+func (d *DockerMonitor) logger() *slog.Logger {
+	logger := d.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if d.Quiet {
+		logger = slog.New(levelHandler{level: slog.LevelError, Handler: logger.Handler()})
+	}
+	return logger
+}
+
+// levelHandler drops records below level before they reach the wrapped handler.
+type levelHandler struct {
+	level slog.Level
+	slog.Handler
+}
+
+func (h levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{level: h.level, Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{level: h.level, Handler: h.Handler.WithGroup(name)}
+}
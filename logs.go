@@ -0,0 +1,146 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLogTail     = 100
+	DefaultLogMaxBytes = 64 * 1024
+	DefaultLogTimeout  = 5 * time.Second
+)
+
+// ContainerLogs holds the most recent log output of a container.
+// Truncated is set when the output hit the byte cap or the collection timed
+// out, so consumers know Output is incomplete.
+type ContainerLogs struct {
+	Output    string `json:"output"`
+	Truncated bool   `json:"truncated"`
+	TimedOut  bool   `json:"timedOut,omitempty"`
+}
+
+// CheckContainerLogs collects the last Tail log lines of every container,
+// keeping at most MaxBytes per container and giving each `docker logs` call
+// at most Timeout to finish. Zero values fall back to the defaults above.
+type CheckContainerLogs struct {
+	dockerMonitor *DockerMonitor
+	Tail          int
+	MaxBytes      int
+	Timeout       time.Duration
+}
+
+func (c CheckContainerLogs) Name() string        { return "container-logs" }
+func (c CheckContainerLogs) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerLogs) Execute(ctx context.Context, env string) error {
+	tail, maxBytes, timeout := c.Tail, c.MaxBytes, c.Timeout
+	if tail <= 0 {
+		tail = DefaultLogTail
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultLogMaxBytes
+	}
+	if timeout <= 0 {
+		timeout = DefaultLogTimeout
+	}
+
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	logsByID := make(map[string]*ContainerLogs)
+	truncated := 0
+	for _, cont := range dockerEnv.ContainersInfo {
+		logs, err := c.collect(ctx, env, cont.ID, tail, maxBytes, timeout)
+		if err != nil {
+			return err
+		}
+		if logs.Truncated {
+			truncated += 1
+		}
+		logsByID[cont.ID] = logs
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			if logs, ok := logsByID[e.ContainersInfo[i].ID]; ok {
+				e.ContainersInfo[i].Logs = logs
+			}
+		}
+	})
+	c.dockerMonitor.logger().Info("container logs", "environment", env, "truncated", truncated)
+	return nil
+}
+
+func (c CheckContainerLogs) collect(ctx context.Context, env, id string, tail, maxBytes int, timeout time.Duration) (*ContainerLogs, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	cmd, release := c.dockerMonitor.DockerCommand(ctx, env, "logs", "--tail", strconv.Itoa(tail), id)
+	defer release()
+	output := &cappedBuffer{max: maxBytes}
+	// docker logs replays the container's stderr on its own stderr, so collect both.
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+
+	logs := &ContainerLogs{Output: output.buf.String(), Truncated: output.truncated}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logs.Truncated = true
+		logs.TimedOut = true
+		return logs, nil
+	}
+	if err != nil {
+		return nil, commandErrorWithStderr(cmd, err, output.buf.Bytes())
+	}
+	return logs, nil
+}
+
+// cappedBuffer keeps the first max bytes written to it and silently discards
+// the rest, so a noisy process can't grow memory without bound.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.max - b.buf.Len(); len(p) > remaining {
+		b.buf.Write(p[:max(remaining, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// FollowLogs streams a container's log output, starting with its last
+// DefaultLogTail lines, to w until ctx is cancelled, which is the normal way
+// to stop it and returns ctx's error, or until the container stops. On
+// cancellation `docker logs -f` is killed and FollowLogs only returns once it
+// exited and its output was copied, so nothing is left running.
+func (d *DockerMonitor) FollowLogs(ctx context.Context, env, container string, w io.Writer) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	cmd, release := d.DockerCommand(ctx, env, "logs", "-f", "--tail", strconv.Itoa(DefaultLogTail), container)
+	defer release()
+	// As one writer, w is never written to from two goroutines at once.
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Bounds the wait for the output copy should a child of docker, such as
+	// ssh's, keep the pipe open after the kill.
+	cmd.WaitDelay = time.Second
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return commandError(cmd, err)
+	}
+	return nil
+}
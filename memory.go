@@ -0,0 +1,55 @@
+package dockermonitor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+type CheckMemoryLimits struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckMemoryLimits) Name() string        { return "memory-limits" }
+func (c CheckMemoryLimits) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records each container's memory limit and lists the running
+// containers without one, which can run the whole host out of memory.
+func (c CheckMemoryLimits) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.HostConfig.Memory}}")
+	if err != nil {
+		return err
+	}
+
+	limits := make(map[string]int64)
+	var unbounded []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		memory, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		limit, err := strconv.ParseInt(strings.TrimSpace(memory), 10, 64)
+		if err != nil {
+			return err
+		}
+		limits[cont.ID] = limit
+		if limit == 0 && cont.State == "running" {
+			unbounded = append(unbounded, cont.Names)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].MemoryLimit = limits[e.ContainersInfo[i].ID]
+		}
+		e.UnboundedMemory = unbounded
+	})
+	c.dockerMonitor.logger().Info("container memory limits", "environment", env, "unbounded", len(unbounded))
+	return nil
+}
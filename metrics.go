@@ -0,0 +1,179 @@
+package dockermonitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// environmentMetric describes a per-environment gauge. The same definitions
+// back every metrics output so names and help texts stay consistent.
+// Measurement and Field name the gauge in InfluxDB line protocol.
+type environmentMetric struct {
+	Name        string
+	Help        string
+	Measurement string
+	Field       string
+	Value       func(e DockerEnvironment) float64
+}
+
+const containersPerImageMetric = "docker_containers_per_image"
+
+var environmentMetrics = []environmentMetric{
+	{
+		Name:        "docker_containers_running",
+		Help:        "Number of running containers.",
+		Measurement: "docker_containers",
+		Field:       "running",
+		Value:       func(e DockerEnvironment) float64 { return float64(e.RunningContainers) },
+	},
+	{
+		Name:        "docker_containers_stopped",
+		Help:        "Number of stopped containers.",
+		Measurement: "docker_containers",
+		Field:       "stopped",
+		Value:       func(e DockerEnvironment) float64 { return float64(e.StoppedContainers) },
+	},
+	{
+		Name:        "docker_images_local",
+		Help:        "Number of local images.",
+		Measurement: "docker_images",
+		Field:       "local",
+		Value:       func(e DockerEnvironment) float64 { return float64(e.TotalLocalDockerImages) },
+	},
+}
+
+// WritePrometheusText renders the per-environment gauges in the Prometheus
+// text exposition format, e.g. for the node_exporter textfile collector.
+func (d *DockerMonitor) WritePrometheusText(w io.Writer) error {
+	snapshot := d.Snapshot()
+	bw := bufio.NewWriter(w)
+	for _, m := range environmentMetrics {
+		fmt.Fprintf(bw, "# HELP %s %s\n", m.Name, m.Help)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", m.Name)
+		for _, dockerEnv := range snapshot.DockerEnvironments {
+			fmt.Fprintf(bw, "%s{environment=\"%s\"} %g\n", m.Name, escapeLabelValue(dockerEnv.Environment), m.Value(dockerEnv))
+		}
+	}
+
+	fmt.Fprintf(bw, "# HELP %s %s\n", containersPerImageMetric, "Number of running containers per image.")
+	fmt.Fprintf(bw, "# TYPE %s gauge\n", containersPerImageMetric)
+	for _, dockerEnv := range snapshot.DockerEnvironments {
+		perImage := dockerEnv.ContainersPerImage()
+		for _, image := range sortedKeys(perImage) {
+			fmt.Fprintf(bw, "%s{environment=\"%s\",image=\"%s\"} %d\n", containersPerImageMetric,
+				escapeLabelValue(dockerEnv.Environment), escapeLabelValue(image), perImage[image])
+		}
+	}
+	return bw.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+// PrometheusFormatter adapts WritePrometheusText to the Formatter interface.
+type PrometheusFormatter struct{}
+
+func (f PrometheusFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	return d.WritePrometheusText(w)
+}
+
+// PrometheusExporter adds counters derived from consecutive collections to
+// the gauges of WritePrometheusText. Call Observe after every run.
+type PrometheusExporter struct {
+	monitor *DockerMonitor
+
+	mu sync.Mutex
+	// running holds, per environment, whether each container ID was running
+	// at the previous observation.
+	running map[string]map[string]bool
+	starts  map[string]int
+	stops   map[string]int
+}
+
+func NewPrometheusExporter(d *DockerMonitor) *PrometheusExporter {
+	return &PrometheusExporter{
+		monitor: d,
+		running: make(map[string]map[string]bool),
+		starts:  make(map[string]int),
+		stops:   make(map[string]int),
+	}
+}
+
+// Observe diffs the monitor's current containers against the previous
+// observation and counts containers that started or stopped in between.
+// The first observation of an environment only records the baseline.
+func (p *PrometheusExporter) Observe() {
+	snapshot := p.monitor.Snapshot()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, dockerEnv := range snapshot.DockerEnvironments {
+		// Running means not exited, matching RunningContainers.
+		current := make(map[string]bool)
+		for _, cont := range dockerEnv.ContainersInfo {
+			current[cont.ID] = cont.State != "exited"
+		}
+
+		previous, seen := p.running[dockerEnv.Environment]
+		p.running[dockerEnv.Environment] = current
+		if !seen {
+			// Export zeros right away so rate() has a starting point.
+			p.starts[dockerEnv.Environment] = 0
+			p.stops[dockerEnv.Environment] = 0
+			continue
+		}
+		for id, running := range current {
+			if running && !previous[id] {
+				p.starts[dockerEnv.Environment]++
+			}
+			if !running && previous[id] {
+				p.stops[dockerEnv.Environment]++
+			}
+		}
+		// Running containers that were removed stopped as well.
+		for id, wasRunning := range previous {
+			if _, found := current[id]; !found && wasRunning {
+				p.stops[dockerEnv.Environment]++
+			}
+		}
+	}
+}
+
+// WritePrometheusText writes the monitor's gauges followed by the counters.
+func (p *PrometheusExporter) WritePrometheusText(w io.Writer) error {
+	if err := p.monitor.WritePrometheusText(w); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	for _, counter := range []struct {
+		name, help string
+		values     map[string]int
+	}{
+		{"docker_container_starts_total", "Containers observed to start between runs.", p.starts},
+		{"docker_container_stops_total", "Containers observed to stop between runs.", p.stops},
+	} {
+		fmt.Fprintf(bw, "# HELP %s %s\n", counter.name, counter.help)
+		fmt.Fprintf(bw, "# TYPE %s counter\n", counter.name)
+		for _, env := range sortedKeys(counter.values) {
+			fmt.Fprintf(bw, "%s{environment=\"%s\"} %d\n", counter.name, escapeLabelValue(env), counter.values[env])
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,680 @@
+// Package dockermonitor collects the state of one or more docker environments
+// by running workflows of actions against them. The dockermonitor command in
+// cmd/dockermonitor is a thin CLI on top of it.
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Action is a single monitoring step run by a Workflow against one
+// environment. Besides the built-in Check* actions, other packages can
+// implement Action and add it to a workflow. An action reads what earlier
+// actions collected with DockerMonitor.Environment, stores its own results
+// with DockerMonitor.UpdateEnvironment (SetExtension for data without a
+// dedicated field) and runs docker through DockerMonitor.DockerCommand.
+type Action interface {
+	// Name identifies the action, e.g. in hooks, logs and results. It should
+	// be unique within a workflow.
+	Name() string
+	// Execute runs the action for env. It should stop when ctx is done.
+	// Returning an error wrapping ErrUnsupported marks the action as skipped.
+	Execute(ctx context.Context, env string) error
+}
+
+// DependentAction is an Action that needs other actions of the same workflow,
+// by name, to run before it. Workflow.ExecuteActions orders actions so their
+// dependencies run first and skips them if a dependency was skipped.
+type DependentAction interface {
+	Action
+	DependsOn() []string
+}
+
+// dockerEnvironment holds properties for a given environment
+type DockerEnvironment struct {
+	Environment string
+	// Host, if set, is an SSH destination (e.g. "deploy@10.0.0.5") docker is run on.
+	Host string `json:",omitempty"`
+	// SocketPath, if set, is the daemon socket, e.g. $XDG_RUNTIME_DIR/docker.sock for rootless docker.
+	SocketPath string `json:",omitempty"`
+	// ComposeFile, if set, is the docker-compose.yml CheckComposeFileDrift compares against.
+	ComposeFile string `json:",omitempty"`
+	// Tags are the environment's config tags, e.g. "production".
+	Tags []string `json:",omitempty"`
+	// MaxConcurrency, if positive, caps how many of its docker commands run at once; see DockerCommand.
+	MaxConcurrency         int `json:",omitempty"`
+	StoppedContainers      int
+	RunningContainers      int
+	DockerVersion          string
+	TotalLocalDockerImages int
+	ContainersInfo         []ContainerInfo
+	ImagesInfo             []ImageInfo
+
+	// CollectedAt is when CheckContainersStatus last listed the containers.
+	CollectedAt time.Time
+
+	// Status and StatusReasons are filled in by ComputeStatus.
+	Status        EnvironmentStatus `json:",omitempty"`
+	StatusReasons []string          `json:",omitempty"`
+
+	// APIVersion and Capabilities are filled in by CheckCapabilities.
+	APIVersion   string
+	Capabilities map[Capability]bool
+
+	// UnusedImages is filled in by CheckImageUsage.
+	UnusedImages []ImageInfo
+	// PortMismatches is filled in by CheckPortBindings.
+	PortMismatches []PortMismatch
+	// LabelViolations is filled in by CheckContainerLabelsPolicy.
+	LabelViolations []LabelViolation
+	// OrphanedContainers is filled in by CheckOrphanedContainers.
+	OrphanedContainers []ContainerInfo
+	// OutdatedImages is filled in by CheckImagePullPolicy.
+	OutdatedImages []OutdatedImage
+	// DaemonArchitecture and ArchMismatches are filled in by CheckCPUArchitecture.
+	DaemonArchitecture string
+	ArchMismatches     []ArchMismatch
+	// TotalLogFileBytes and OversizedLogs are filled in by CheckLogFileSizes.
+	TotalLogFileBytes int64
+	OversizedLogs     []string
+	// LogDrivers, the number of containers per logging driver, and
+	// UnexpectedLogDrivers are filled in by CheckContainerLogDriver.
+	LogDrivers           map[string]int
+	UnexpectedLogDrivers []string
+	// RootContainers is filled in by CheckContainerUser.
+	RootContainers []string
+	// WritableRootfsContainers is filled in by CheckContainerReadOnlyRootfs.
+	WritableRootfsContainers []string
+	// SharedNetworkContainers is filled in by CheckContainerNetworkMode.
+	SharedNetworkContainers []SharedNetwork
+	// SecurityFindings collects the findings of the security actions.
+	SecurityFindings []SecurityFinding
+	// Builders and UnhealthyBuilders are filled in by CheckBuilders.
+	Builders          []BuilderInfo
+	UnhealthyBuilders []string
+	// Secrets, Configs, OrphanedSecrets and OrphanedConfigs are filled in by
+	// CheckSecretsAndConfigs.
+	Secrets         []SwarmObject
+	Configs         []SwarmObject
+	OrphanedSecrets []string
+	OrphanedConfigs []string
+	// GPUContainers and GPUsInUse are filled in by CheckGPUContainers. GPUsInUse
+	// leaves out containers holding all GPUs.
+	GPUContainers []string
+	GPUsInUse     int
+	// UnboundedMemory is filled in by CheckMemoryLimits.
+	UnboundedMemory []string
+	// UnboundedTmpfs is filled in by CheckContainerTmpfsMounts, as container:path.
+	UnboundedTmpfs []string
+	// HeavyDiskWriters is filled in by CheckContainerDiskWrites.
+	HeavyDiskWriters []string
+	// LowNofileContainers is filled in by CheckContainerUlimits.
+	LowNofileContainers []string
+	// ShortStopTimeouts is filled in by CheckContainerStopTimeout.
+	ShortStopTimeouts []string
+	// StartSkewedContainers is filled in by CheckContainerCreatedVsStarted.
+	StartSkewedContainers []string
+	// UnreachableContainers is filled in by CheckContainerNetworkConnectivity.
+	UnreachableContainers []string
+	// Flapping is filled in by CheckContainerHealthHistory.
+	Flapping []string
+	// ZombieContainers is filled in by CheckZombieProcesses.
+	ZombieContainers []string
+	// StaleTagContainers is filled in by CheckContainerImageMismatch.
+	StaleTagContainers []StaleTagContainer
+	// UnmetDependencies is filled in by CheckContainerDependsOn.
+	UnmetDependencies []UnmetDependency
+	// UnsignedProductionImages is filled in by CheckImageSignatures.
+	UnsignedProductionImages []string
+	// MissingProvenance is filled in by CheckImageProvenance.
+	MissingProvenance []string
+	// Volumes, largest first, DanglingVolumes and DanglingVolumeBytes are filled in by CheckVolumeUsage.
+	Volumes             []VolumeInfo
+	DanglingVolumes     []string
+	DanglingVolumeBytes int64
+
+	// ComposeDrift is filled in by CheckComposeFileDrift.
+	ComposeDrift *ComposeDrift `json:",omitempty"`
+	// Daemon is filled in by CheckDaemonConfig.
+	Daemon *DaemonInfo `json:",omitempty"`
+	// HostResources is filled in by CheckHostResources.
+	HostResources *HostResources `json:",omitempty"`
+
+	// DroppedEvents counts events StreamEvents couldn't deliver to a full channel.
+	DroppedEvents int
+
+	// Extensions holds results of custom actions, keyed by the action's
+	// choice of name; see DockerMonitor.SetExtension.
+	Extensions map[string]json.RawMessage `json:",omitempty"`
+}
+
+// DockerMonitor acts as a factory
+type DockerMonitor struct {
+	// mu guards DockerEnvironments; see updateEnvironment and Snapshot.
+	mu                 sync.RWMutex
+	DockerEnvironments []DockerEnvironment
+
+	// GlobalArgs are passed to every docker invocation before the subcommand,
+	// e.g. []string{"--host", "tcp://10.0.0.5:2376", "--tls"}.
+	GlobalArgs []string
+
+	// CommandTimeout, if positive, bounds every single docker invocation,
+	// independent of the deadline of the context the action runs with.
+	// CommandTimeouts overrides it per docker subcommand, e.g.
+	// {"version": 5 * time.Second, "images": 2 * time.Minute}.
+	CommandTimeout  time.Duration
+	CommandTimeouts map[string]time.Duration
+
+	// Retries is how often workflows run a failed action again, see
+	// Workflow.Monitor. RetryBudget, if set, caps retries across all of them.
+	Retries     int
+	RetryBudget *RetryBudget
+
+	// Redaction, if set, masks secrets in the collected data of every
+	// Snapshot, so formatters, exporters and the server never output them.
+	Redaction *RedactionPolicy
+
+	// Logger receives the actions' informational output; slog.Default() is
+	// used when nil. Quiet drops everything below error level.
+	Logger *slog.Logger
+	Quiet  bool
+
+	// SSHControlPersist is how long idle SSH master connections to remote
+	// environments are kept open; DefaultSSHControlPersist when zero.
+	SSHControlPersist time.Duration
+	sshOnce           sync.Once
+	sshControlDir     string
+
+	// calls coalesces identical concurrent DockerOutput calls.
+	calls singleflight.Group
+
+	// slots holds a semaphore per environment with a MaxConcurrency.
+	slotsMu sync.Mutex
+	slots   map[string]chan struct{}
+
+	// StatsWindow, if above 1, makes CheckContainerStats and
+	// CheckContainerCgroupStats keep that many recent samples per container,
+	// across runs, and set the moving averages of
+	// ContainerStats, which are far less spiky than single samples.
+	StatsWindow  int
+	statsMu      sync.Mutex
+	statsSamples map[[3]string]*statsSamples // by environment, action and container ID
+}
+
+const DefaultSSHControlPersist = time.Minute
+
+// containerInfo holds container data
+type ContainerInfo struct {
+	Command      string `json:"command"`
+	CreatedAt    string `json:"createdAt"`
+	ID           string `json:"id"`
+	Image        string `json:"image"`
+	Labels       string `json:"labels"`
+	LocalVolumes string `json:"localVolumes"`
+	Mounts       string `json:"mounts"`
+	Names        string `json:"names"`
+	Networks     string `json:"networks"`
+	Ports        string `json:"ports"`
+	RunningFor   string `json:"runningFor"`
+	Size         string `json:"size"`
+	State        string `json:"state"`
+	Status       string `json:"status"`
+
+	// NetworkAddresses is filled in by CheckContainerIPs.
+	NetworkAddresses []ContainerNetwork `json:"networkAddresses,omitempty"`
+	// Logs is filled in by CheckContainerLogs.
+	Logs *ContainerLogs `json:"logs,omitempty"`
+	// ConfigDrift and ConfigDriftDescription are filled in by CheckContainerUpdatedConfig.
+	ConfigDrift            bool   `json:"configDrift,omitempty"`
+	ConfigDriftDescription string `json:"configDriftDescription,omitempty"`
+	// LogFileSize is filled in by CheckLogFileSizes.
+	LogFileSize int64 `json:"logFileSize,omitempty"`
+	// LogDriver is filled in by CheckContainerLogDriver.
+	LogDriver string `json:"logDriver,omitempty"`
+	// Zombies is filled in by CheckZombieProcesses.
+	Zombies int `json:"zombies,omitempty"`
+	// Connectivity is filled in by CheckContainerNetworkConnectivity.
+	Connectivity string `json:"connectivity,omitempty"`
+	// StopTimeout, in seconds, and StopSignal are filled in by
+	// CheckContainerStopTimeout, with docker's defaults for containers that
+	// don't set them.
+	StopTimeout int    `json:"stopTimeout,omitempty"`
+	StopSignal  string `json:"stopSignal,omitempty"`
+	// User is filled in by CheckContainerUser; empty means the image default.
+	User string `json:"user,omitempty"`
+	// Stats is filled in by CheckContainerStats and CheckContainerNetworkIO.
+	Stats *ContainerStats `json:"stats,omitempty"`
+	// RunCommand is filled in by CheckContainerCreateArgs.
+	RunCommand string `json:"runCommand,omitempty"`
+	// GPUs is filled in by CheckGPUContainers; AllGPUs for `--gpus all`.
+	GPUs int `json:"gpus,omitempty"`
+	// MemoryLimit is filled in by CheckMemoryLimits, in bytes; 0 means unlimited.
+	MemoryLimit int64 `json:"memoryLimit,omitempty"`
+	// TmpfsMounts is filled in by CheckContainerTmpfsMounts.
+	TmpfsMounts []TmpfsMount `json:"tmpfsMounts,omitempty"`
+	// StartSkewSeconds is filled in by CheckContainerCreatedVsStarted.
+	StartSkewSeconds int64 `json:"startSkewSeconds,omitempty"`
+	// Entrypoint and Cmd are filled in by CheckContainerEntrypointCmd.
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Cmd        []string `json:"cmd,omitempty"`
+	// CapAdd and CapDrop are filled in by CheckContainerCapabilities, without the CAP_ prefix.
+	CapAdd  []string `json:"capAdd,omitempty"`
+	CapDrop []string `json:"capDrop,omitempty"`
+	// SecurityOpt and AppArmorProfile are filled in by CheckContainerSecurityOpt.
+	SecurityOpt     []string `json:"securityOpt,omitempty"`
+	AppArmorProfile string   `json:"appArmorProfile,omitempty"`
+	// Ulimits is filled in by CheckContainerUlimits; empty means the daemon defaults.
+	Ulimits []Ulimit `json:"ulimits,omitempty"`
+}
+
+// containerInfo holds image data
+type ImageInfo struct {
+	Containers   string `json:"containers"`
+	CreatedAt    string `json:"createdAt"`
+	CreatedSince string `json:"createSince"`
+	Digest       string `json:"digest"`
+	ID           string `json:"id"`
+	Repository   string `json:"repository"`
+	SharedSize   string `json:"sharedSize"`
+	Size         string `json:"size"`
+	Tag          string `json:"tag"`
+	UniqueSize   string `json:"uniqueSize"`
+	VirtualSize  string `json:"virtualSize"`
+	// Platform and BaseOS are filled in by CheckImageBaseOS.
+	Platform string `json:"platform,omitempty"`
+	BaseOS   string `json:"baseOS,omitempty"`
+	// SignatureStatus is filled in by CheckImageSignatures.
+	SignatureStatus string `json:"signatureStatus,omitempty"`
+	// Provenance is filled in by CheckImageProvenance; nil without OCI labels.
+	Provenance *ImageProvenance `json:"provenance,omitempty"`
+}
+
+// function to create an instance of DockerMonitor
+func NewDockerMonitor(envs []string) *DockerMonitor {
+	const InitialContainers = 0
+	const InitialLocalImages = 0
+	var dockerEnvironments []DockerEnvironment
+
+	for _, env := range envs {
+		dockerEnvironments = append(dockerEnvironments, DockerEnvironment{
+			Environment:            env,
+			StoppedContainers:      InitialContainers,
+			RunningContainers:      InitialContainers,
+			DockerVersion:          "",
+			TotalLocalDockerImages: InitialLocalImages,
+			ContainersInfo:         []ContainerInfo{},
+			ImagesInfo:             []ImageInfo{},
+		})
+	}
+	return &DockerMonitor{
+		DockerEnvironments: dockerEnvironments,
+	}
+}
+
+func (d *DockerMonitor) CallCapabilities() Action {
+	return &CheckCapabilities{
+		dockerMonitor: d,
+	}
+}
+
+func (d *DockerMonitor) CallDaemonConfig() Action {
+	return &CheckDaemonConfig{
+		dockerMonitor: d,
+	}
+}
+
+func (d *DockerMonitor) CallDockerVersion() Action {
+	return &CheckDockerVersion{
+		dockerMonitor: d,
+	}
+}
+
+func (d *DockerMonitor) CallContainersStatus() Action {
+	return &CheckContainersStatus{
+		dockerMonitor: d,
+	}
+}
+
+func (d *DockerMonitor) CallLocalImages() Action {
+	return &CheckLocalImages{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerIPs needs CallContainersStatus to run first in the same workflow.
+func (d *DockerMonitor) CallContainerIPs() Action {
+	return &CheckContainerIPs{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerLogs needs CallContainersStatus to run first in the same workflow.
+// Zero values for tail, maxBytes or timeout use the package defaults.
+func (d *DockerMonitor) CallContainerLogs(tail, maxBytes int, timeout time.Duration) Action {
+	return &CheckContainerLogs{
+		dockerMonitor: d,
+		Tail:          tail,
+		MaxBytes:      maxBytes,
+		Timeout:       timeout,
+	}
+}
+
+// CallImageUsage needs CallContainersStatus and CallLocalImages to run first.
+func (d *DockerMonitor) CallImageUsage() Action {
+	return &CheckImageUsage{
+		dockerMonitor: d,
+	}
+}
+
+// CallOrphanedContainers needs CallContainersStatus and CallLocalImages to run first.
+func (d *DockerMonitor) CallOrphanedContainers() Action {
+	return &CheckOrphanedContainers{
+		dockerMonitor: d,
+	}
+}
+
+// CallImagePullPolicy compares local digests with the registry, using tag as
+// the remote tag or each image's own tag when empty. It needs CallLocalImages first.
+func (d *DockerMonitor) CallImagePullPolicy(tag string) Action {
+	return &CheckImagePullPolicy{
+		dockerMonitor: d,
+		Tag:           tag,
+	}
+}
+
+// CallCPUArchitecture needs CallLocalImages to run first.
+func (d *DockerMonitor) CallCPUArchitecture() Action {
+	return &CheckCPUArchitecture{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerEntrypointCmd needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerEntrypointCmd() Action {
+	return &CheckContainerEntrypointCmd{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerCreateArgs needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerCreateArgs() Action {
+	return &CheckContainerCreateArgs{
+		dockerMonitor: d,
+	}
+}
+
+// CallGPUContainers needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallGPUContainers() Action {
+	return &CheckGPUContainers{
+		dockerMonitor: d,
+	}
+}
+
+// CallComposeFileDrift compares environments that have a ComposeFile with
+// their running containers. It needs CallContainersStatus first.
+func (d *DockerMonitor) CallComposeFileDrift() Action {
+	return &CheckComposeFileDrift{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerUpdatedConfig needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerUpdatedConfig() Action {
+	return &CheckContainerUpdatedConfig{
+		dockerMonitor: d,
+	}
+}
+
+// CallLogFileSizes flags containers whose log file is larger than threshold
+// bytes, DefaultLogFileThreshold when zero. It needs CallContainersStatus first.
+func (d *DockerMonitor) CallLogFileSizes(threshold int64) Action {
+	return &CheckLogFileSizes{
+		dockerMonitor: d,
+		Threshold:     threshold,
+	}
+}
+
+// CallContainerUser needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerUser() Action {
+	return &CheckContainerUser{
+		dockerMonitor: d,
+	}
+}
+
+// CallImageBaseOS needs CallLocalImages to run first. See
+// CheckImageBaseOS.ReadOSRelease for readOSRelease.
+func (d *DockerMonitor) CallImageBaseOS(readOSRelease bool) Action {
+	return &CheckImageBaseOS{
+		dockerMonitor: d,
+		ReadOSRelease: readOSRelease,
+	}
+}
+
+// CallContainerCreatedVsStarted needs CallContainersStatus to run first. A
+// zero threshold means DefaultStartSkewThreshold.
+func (d *DockerMonitor) CallContainerCreatedVsStarted(threshold time.Duration) Action {
+	return &CheckContainerCreatedVsStarted{
+		dockerMonitor: d,
+		Threshold:     threshold,
+	}
+}
+
+// CallImageSignatures needs CallContainersStatus and CallLocalImages to run
+// first. An empty cosignKey checks Docker Content Trust instead of cosign.
+func (d *DockerMonitor) CallImageSignatures(cosignKey string) Action {
+	return &CheckImageSignatures{
+		dockerMonitor: d,
+		CosignKey:     cosignKey,
+	}
+}
+
+// CallImageProvenance needs CallContainersStatus and CallLocalImages to run
+// first.
+func (d *DockerMonitor) CallImageProvenance() Action {
+	return &CheckImageProvenance{
+		dockerMonitor: d,
+	}
+}
+
+func (d *DockerMonitor) CallVolumeUsage() Action {
+	return &CheckVolumeUsage{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerTmpfsMounts needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerTmpfsMounts() Action {
+	return &CheckContainerTmpfsMounts{
+		dockerMonitor: d,
+	}
+}
+
+// CallMemoryLimits needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallMemoryLimits() Action {
+	return &CheckMemoryLimits{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerUlimits needs CallContainersStatus to run first. A zero
+// minNofile means DefaultMinNofile.
+func (d *DockerMonitor) CallContainerUlimits(minNofile int64) Action {
+	return &CheckContainerUlimits{
+		dockerMonitor: d,
+		MinNofile:     minNofile,
+	}
+}
+
+func (d *DockerMonitor) CallHostResources() Action {
+	return &CheckHostResources{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerHealthHistory keeps the health history of the containers
+// across runs of the returned action, which flags containers whose health
+// changed transitions times within window; zero values mean the defaults. It
+// needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerHealthHistory(window time.Duration, transitions int) Action {
+	return &CheckContainerHealthHistory{
+		dockerMonitor: d,
+		Window:        window,
+		Transitions:   transitions,
+		history:       &healthHistory{samples: make(map[[2]string][]healthSample)},
+	}
+}
+
+// CallContainerDiskWrites keeps the write totals of the containers across
+// runs of the returned action, which flags containers writing faster than
+// maxWriteRate bytes per second; zero means DefaultMaxDiskWriteRate. It
+// needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerDiskWrites(maxWriteRate float64) Action {
+	return &CheckContainerDiskWrites{
+		dockerMonitor: d,
+		MaxWriteRate:  maxWriteRate,
+		history:       &diskWriteHistory{samples: make(map[[2]string]diskWriteSample)},
+	}
+}
+
+// CallZombieProcesses keeps the zombie counts across runs of the returned
+// action. It needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallZombieProcesses() Action {
+	return &CheckZombieProcesses{
+		dockerMonitor: d,
+		history:       &zombieHistory{counts: make(map[[2]string]int)},
+	}
+}
+
+// CallContainerLogDriver flags containers on a logging driver other than
+// expected, or than the daemon's default when none are given. It needs
+// CallContainersStatus to run first, and CallDaemonConfig without expected.
+func (d *DockerMonitor) CallContainerLogDriver(expected ...string) Action {
+	return &CheckContainerLogDriver{
+		dockerMonitor: d,
+		Expected:      expected,
+	}
+}
+
+// CallContainerStopTimeout needs CallContainersStatus to run first. A zero
+// minStopTimeout means DefaultMinStopTimeout.
+func (d *DockerMonitor) CallContainerStopTimeout(minStopTimeout int) Action {
+	return &CheckContainerStopTimeout{
+		dockerMonitor:  d,
+		MinStopTimeout: minStopTimeout,
+	}
+}
+
+// CallContainerDependsOn needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerDependsOn() Action {
+	return &CheckContainerDependsOn{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerSecurityOpt needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerSecurityOpt() Action {
+	return &CheckContainerSecurityOpt{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerCapabilities needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerCapabilities() Action {
+	return &CheckContainerCapabilities{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerImageMismatch needs CallContainersStatus and CallLocalImages
+// to run first.
+func (d *DockerMonitor) CallContainerImageMismatch() Action {
+	return &CheckContainerImageMismatch{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerNetworkMode needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerNetworkMode() Action {
+	return &CheckContainerNetworkMode{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerReadOnlyRootfs needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerReadOnlyRootfs() Action {
+	return &CheckContainerReadOnlyRootfs{
+		dockerMonitor: d,
+	}
+}
+
+func (d *DockerMonitor) CallBuilders() Action {
+	return &CheckBuilders{
+		dockerMonitor: d,
+	}
+}
+
+// CallSecretsAndConfigs only runs against Swarm managers.
+func (d *DockerMonitor) CallSecretsAndConfigs() Action {
+	return &CheckSecretsAndConfigs{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerStats needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerStats() Action {
+	return &CheckContainerStats{
+		dockerMonitor: d,
+	}
+}
+
+// CallContainerCgroupStats keeps each container's CPU reading across runs of
+// the returned action. It needs CallContainersStatus to run first and falls
+// back to CallContainerStats where cgroup v2 can't be read.
+func (d *DockerMonitor) CallContainerCgroupStats() Action {
+	return &CheckContainerCgroupStats{
+		dockerMonitor: d,
+		history:       &cgroupCPUHistory{samples: make(map[[2]string]cgroupCPUSample)},
+	}
+}
+
+// CallContainerNetworkIO samples stats interval apart, DefaultStatsSampleInterval
+// when zero. It needs CallContainersStatus to run first.
+func (d *DockerMonitor) CallContainerNetworkIO(interval time.Duration) Action {
+	return &CheckContainerNetworkIO{
+		dockerMonitor: d,
+		Interval:      interval,
+	}
+}
+
+// CallPortBindings compares published ports against expected, keyed by
+// container name or image repository. It needs CallContainersStatus first.
+func (d *DockerMonitor) CallPortBindings(expected map[string][]string) Action {
+	return &CheckPortBindings{
+		dockerMonitor: d,
+		Expected:      expected,
+	}
+}
+
+// CallContainerNetworkConnectivity runs probe in every running container. It
+// needs CallContainersStatus first.
+func (d *DockerMonitor) CallContainerNetworkConnectivity(probe ConnectivityProbe) Action {
+	return &CheckContainerNetworkConnectivity{
+		dockerMonitor: d,
+		Probe:         probe,
+	}
+}
+
+// CallContainerLabelsPolicy checks container labels against policy. It needs
+// CallContainersStatus first.
+func (d *DockerMonitor) CallContainerLabelsPolicy(policy LabelPolicy) Action {
+	return &CheckContainerLabelsPolicy{
+		dockerMonitor: d,
+		Policy:        policy,
+	}
+}
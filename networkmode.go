@@ -0,0 +1,56 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+)
+
+// SharedNetwork is a running container that doesn't have a network namespace
+// of its own: Mode is "host" or "container:<name|id>".
+type SharedNetwork struct {
+	Container string `json:"container"`
+	Mode      string `json:"mode"`
+}
+
+type CheckContainerNetworkMode struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerNetworkMode) Name() string        { return "container-network-mode" }
+func (c CheckContainerNetworkMode) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute lists running containers on the host's network, which bypasses
+// published port isolation and can collide with host services, or on
+// another container's network.
+func (c CheckContainerNetworkMode) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.HostConfig.NetworkMode}}")
+	if err != nil {
+		return err
+	}
+
+	var shared []SharedNetwork
+	var findings []SecurityFinding
+	for _, cont := range dockerEnv.ContainersInfo {
+		mode := strings.TrimSpace(inspected[cont.ID])
+		if cont.State != "running" || (mode != "host" && !strings.HasPrefix(mode, "container:")) {
+			continue
+		}
+		shared = append(shared, SharedNetwork{Container: cont.Names, Mode: mode})
+		if mode == "host" {
+			findings = append(findings, SecurityFinding{Container: cont.Names, Check: "host-network", Detail: "uses the host's network namespace"})
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.SharedNetworkContainers = shared
+		e.setSecurityFindings("host-network", findings)
+	})
+	c.dockerMonitor.logger().Info("container network modes", "environment", env, "shared", len(shared))
+	return nil
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package dockermonitor
+
+const raceEnabled = false
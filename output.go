@@ -0,0 +1,162 @@
+package dockermonitor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formatter renders the data collected by a DockerMonitor to w.
+type Formatter interface {
+	Format(w io.Writer, d *DockerMonitor) error
+}
+
+// JSONFormatter writes all environments as a single JSON document, indented
+// unless Compact is set. When Fields is set containers only carry those fields;
+// KeyCase renames the struct fields.
+type JSONFormatter struct {
+	Compact bool
+	Fields  []string
+	KeyCase KeyCase
+}
+
+func (f JSONFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	encoder := json.NewEncoder(w)
+	if !f.Compact {
+		encoder.SetIndent("", "  ")
+	}
+	var environments []any
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		environments = append(environments, withSelectedFields(dockerEnv, f.Fields, f.KeyCase))
+	}
+	return encoder.Encode(environments)
+}
+
+// NDJSONFormatter writes newline-delimited JSON, one object per environment,
+// or one object per container when PerContainer is set. This is the shape
+// log pipelines such as jq, Logstash or Loki expect.
+type NDJSONFormatter struct {
+	PerContainer bool
+	Fields       []string
+	KeyCase      KeyCase
+}
+
+// containerRecord is a single NDJSON line in per-container mode.
+type containerRecord struct {
+	Environment string `json:"environment"`
+	ContainerInfo
+}
+
+func (f NDJSONFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	encoder := json.NewEncoder(w)
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		if !f.PerContainer {
+			if err := encoder.Encode(withSelectedFields(dockerEnv, f.Fields, f.KeyCase)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, cont := range dockerEnv.ContainersInfo {
+			var record any = containerRecord{Environment: dockerEnv.Environment, ContainerInfo: cont}
+			if len(f.Fields) > 0 {
+				record = selectedContainerFields(cont, f.Fields, f.KeyCase, casedObject{{f.KeyCase.apply("environment"), dockerEnv.Environment}})
+			} else if f.KeyCase != KeyCaseDefault {
+				record = withKeyCase(record, f.KeyCase)
+			}
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CSVFormatter writes one row per container, prefixed with its environment.
+// Fields selects the columns and defaults to the `docker ps` fields.
+type CSVFormatter struct {
+	Fields []string
+}
+
+func (f CSVFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	fields := f.Fields
+	if len(fields) == 0 {
+		fields = dockerPsFields
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"environment"}, fields...))
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		for _, cont := range dockerEnv.ContainersInfo {
+			writer.Write(append([]string{dockerEnv.Environment}, containerFieldStrings(cont, fields)...))
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// TableFormatter writes an aligned, human readable table of containers.
+// Fields selects the columns and defaults to a short set.
+type TableFormatter struct {
+	Fields []string
+}
+
+func (f TableFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	fields := f.Fields
+	if len(fields) == 0 {
+		fields = defaultTableFields
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := []string{"ENVIRONMENT"}
+	for _, field := range fields {
+		header = append(header, strings.ToUpper(field))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		for _, cont := range dockerEnv.ContainersInfo {
+			row := append([]string{dockerEnv.Environment}, containerFieldStrings(cont, fields)...)
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+	}
+	return tw.Flush()
+}
+
+// FormatOptions tunes the formatters returned by FormatterFor.
+type FormatOptions struct {
+	// Compact writes single-line JSON, better suited to log ingestion.
+	Compact bool
+	// Fields restricts container output to these ContainerInfo json names,
+	// e.g. []string{"id", "names", "state", "image"}.
+	Fields []string
+	// KeyCase renames JSON struct fields for the json and ndjson formats.
+	KeyCase KeyCase
+}
+
+// FormatterFor returns the formatter registered under name.
+func FormatterFor(name string, opts FormatOptions) (Formatter, error) {
+	if err := validateContainerFields(opts.Fields); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "json":
+		return JSONFormatter{Compact: opts.Compact, Fields: opts.Fields, KeyCase: opts.KeyCase}, nil
+	case "ndjson":
+		return NDJSONFormatter{Fields: opts.Fields, KeyCase: opts.KeyCase}, nil
+	case "ndjson-containers":
+		return NDJSONFormatter{PerContainer: true, Fields: opts.Fields, KeyCase: opts.KeyCase}, nil
+	case "csv":
+		return CSVFormatter{Fields: opts.Fields}, nil
+	case "table":
+		return TableFormatter{Fields: opts.Fields}, nil
+	case "matrix":
+		return MatrixFormatter{}, nil
+	case "matrix-json":
+		return MatrixFormatter{JSON: true, Compact: opts.Compact}, nil
+	case "prom":
+		return PrometheusFormatter{}, nil
+	case "influx":
+		return InfluxFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", name)
+}
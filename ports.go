@@ -0,0 +1,150 @@
+package dockermonitor
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PortBinding is a single published port of a container.
+type PortBinding struct {
+	HostPort      string `json:"hostPort"`
+	ContainerPort string `json:"containerPort"`
+	Protocol      string `json:"protocol"`
+}
+
+func (p PortBinding) String() string {
+	return p.HostPort + ":" + p.ContainerPort + "/" + p.Protocol
+}
+
+// parsePortBindings extracts the published bindings from the Ports column of
+// `docker ps`, e.g. "0.0.0.0:8080->80/tcp, :::8080->80/tcp, 9000/tcp".
+// Exposed-only ports are ignored, port ranges are expanded and the duplicate
+// IPv4/IPv6 entries are collapsed.
+func parsePortBindings(ports string) []PortBinding {
+	var bindings []PortBinding
+	for _, entry := range strings.Split(ports, ",") {
+		host, target, published := strings.Cut(strings.TrimSpace(entry), "->")
+		if !published {
+			continue
+		}
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[i+1:]
+		}
+		containerPorts, protocol, _ := strings.Cut(target, "/")
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		hostRange, containerRange := expandPortRange(host), expandPortRange(containerPorts)
+		for i, containerPort := range containerRange {
+			binding := PortBinding{HostPort: containerPort, ContainerPort: containerPort, Protocol: protocol}
+			if i < len(hostRange) {
+				binding.HostPort = hostRange[i]
+			}
+			if !slices.Contains(bindings, binding) {
+				bindings = append(bindings, binding)
+			}
+		}
+	}
+	return bindings
+}
+
+func expandPortRange(ports string) []string {
+	first, last, isRange := strings.Cut(ports, "-")
+	if !isRange {
+		return []string{ports}
+	}
+	start, err1 := strconv.Atoi(first)
+	end, err2 := strconv.Atoi(last)
+	if err1 != nil || err2 != nil || end < start {
+		return []string{ports}
+	}
+	var expanded []string
+	for port := start; port <= end; port++ {
+		expanded = append(expanded, strconv.Itoa(port))
+	}
+	return expanded
+}
+
+// matches reports whether the binding satisfies an expected port spec of the
+// form "[hostPort:]containerPort[/protocol]". Without a host port any
+// published host port is accepted.
+func (p PortBinding) matches(spec string) bool {
+	spec, protocol, _ := strings.Cut(spec, "/")
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	hostPort, containerPort, withHost := strings.Cut(spec, ":")
+	if !withHost {
+		containerPort, hostPort = hostPort, ""
+	}
+	return p.Protocol == protocol && p.ContainerPort == containerPort && (hostPort == "" || p.HostPort == hostPort)
+}
+
+// PortMismatch reports a container whose published ports differ from the expected set.
+type PortMismatch struct {
+	Container  string   `json:"container"`
+	Expected   []string `json:"expected"`
+	Actual     []string `json:"actual"`
+	Unexpected []string `json:"unexpected,omitempty"`
+	Missing    []string `json:"missing,omitempty"`
+}
+
+type CheckPortBindings struct {
+	dockerMonitor *DockerMonitor
+	// Expected maps a container name or image repository to its allowed port specs.
+	Expected map[string][]string
+}
+
+func (c CheckPortBindings) Name() string        { return "port-bindings" }
+func (c CheckPortBindings) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckPortBindings) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var mismatches []PortMismatch
+	for _, cont := range dockerEnv.ContainersInfo {
+		expected, found := c.expectedFor(cont)
+		if !found {
+			continue
+		}
+		if mismatch, drifted := comparePorts(cont, expected); drifted {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.PortMismatches = mismatches
+	})
+	c.dockerMonitor.logger().Info("port bindings", "environment", env, "mismatches", len(mismatches))
+	return nil
+}
+
+// expectedFor looks the container up by name first, then by image repository.
+func (c CheckPortBindings) expectedFor(cont ContainerInfo) ([]string, bool) {
+	if expected, found := c.Expected[cont.Names]; found {
+		return expected, true
+	}
+	repository, _, _ := strings.Cut(cont.Image, ":")
+	expected, found := c.Expected[repository]
+	return expected, found
+}
+
+func comparePorts(cont ContainerInfo, expected []string) (PortMismatch, bool) {
+	mismatch := PortMismatch{Container: cont.Names, Expected: expected}
+	actual := parsePortBindings(cont.Ports)
+	for _, binding := range actual {
+		mismatch.Actual = append(mismatch.Actual, binding.String())
+		if !slices.ContainsFunc(expected, binding.matches) {
+			mismatch.Unexpected = append(mismatch.Unexpected, binding.String())
+		}
+	}
+	for _, spec := range expected {
+		if !slices.ContainsFunc(actual, func(b PortBinding) bool { return b.matches(spec) }) {
+			mismatch.Missing = append(mismatch.Missing, spec)
+		}
+	}
+	return mismatch, len(mismatch.Unexpected) > 0 || len(mismatch.Missing) > 0
+}
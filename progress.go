@@ -0,0 +1,48 @@
+package dockermonitor
+
+import "sync"
+
+// ProgressUpdate is reported each time a workflow finishes: Completed of
+// Total environments are done, the last being Environment.
+type ProgressUpdate struct {
+	Completed   int
+	Total       int
+	Environment string
+	// Err is what the environment's workflow returned, nil on success.
+	Err error
+}
+
+// Progress counts the environments of a run as their workflows finish, e.g.
+// to drive a progress bar or log "completed 3 of 40 environments". Share one
+// Progress between the workflows of a run through Workflow.Progress; it is
+// safe to use from workflows executing concurrently.
+type Progress struct {
+	mu        sync.Mutex
+	total     int
+	completed int
+	report    func(ProgressUpdate)
+}
+
+// NewProgress returns a Progress for total environments calling report
+// after each one. Calls to report are serialized and Completed only grows,
+// so report needs no locking of its own.
+func NewProgress(total int, report func(ProgressUpdate)) *Progress {
+	return &Progress{total: total, report: report}
+}
+
+// Done records that the workflow of env returned err.
+func (p *Progress) Done(env string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed += 1
+	if p.report != nil {
+		p.report(ProgressUpdate{Completed: p.completed, Total: p.total, Environment: env, Err: err})
+	}
+}
+
+// Completed returns how many environments are done.
+func (p *Progress) Completed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.completed
+}
@@ -0,0 +1,82 @@
+package dockermonitor
+
+import (
+	"context"
+)
+
+// OCI annotation labels build tools set to record where an image comes from.
+const (
+	ociRevisionLabel = "org.opencontainers.image.revision"
+	ociSourceLabel   = "org.opencontainers.image.source"
+	ociCreatedLabel  = "org.opencontainers.image.created"
+	ociVersionLabel  = "org.opencontainers.image.version"
+)
+
+// ImageProvenance is the build metadata of an image, from its OCI labels:
+// the git commit and repository it was built from, when, and its version.
+type ImageProvenance struct {
+	Revision string `json:"revision,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Created  string `json:"created,omitempty"`
+	Version  string `json:"version,omitempty"`
+}
+
+type CheckImageProvenance struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckImageProvenance) Name() string { return "image-provenance" }
+func (c CheckImageProvenance) DependsOn() []string {
+	return []string{"containers-status", "local-images"}
+}
+
+// Execute sets Provenance on every local image with OCI labels, and records
+// the images running containers use that can't be mapped back to a commit,
+// because their revision or source label is missing, as MissingProvenance.
+func (c CheckImageProvenance) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, img := range dockerEnv.ImagesInfo {
+		ids = append(ids, img.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .Config.Labels}}")
+	if err != nil {
+		return err
+	}
+
+	provenance := make(map[string]ImageProvenance)
+	for id, raw := range inspected {
+		var labels map[string]string
+		if err := unmarshalLine(raw, "image labels", &labels); err != nil {
+			return err
+		}
+		provenance[id] = ImageProvenance{
+			Revision: labels[ociRevisionLabel],
+			Source:   labels[ociSourceLabel],
+			Created:  labels[ociCreatedLabel],
+			Version:  labels[ociVersionLabel],
+		}
+	}
+
+	var missing []string
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i, img := range e.ImagesInfo {
+			e.ImagesInfo[i].Provenance = nil
+			p, found := provenance[img.ID]
+			if found && p != (ImageProvenance{}) {
+				e.ImagesInfo[i].Provenance = &p
+			}
+			if (p.Revision == "" || p.Source == "") && e.runningFrom(img) {
+				ref := img.Repository + ":" + img.Tag
+				if img.Repository == "<none>" {
+					ref = img.ID
+				}
+				missing = append(missing, ref)
+			}
+		}
+		e.MissingProvenance = missing
+	})
+	c.dockerMonitor.logger().Info("image provenance", "environment", env, "missing", len(missing))
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package dockermonitor
+
+// raceEnabled is set when the race detector instruments the tests, which
+// adds allocations of its own.
+const raceEnabled = true
@@ -0,0 +1,101 @@
+package dockermonitor
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// Redacted replaces what a RedactionPolicy masks.
+const Redacted = "[REDACTED]"
+
+// RedactionPolicy masks secrets that ended up in collected data, e.g. a
+// token in a label, mount path or command line, before it is output. Every
+// match of a pattern in a string field is replaced with Redacted; when a
+// pattern has a capture group only the first group is, so
+// `password=(\S+)` keeps the key visible.
+type RedactionPolicy struct {
+	Patterns []*regexp.Regexp
+}
+
+// NewRedactionPolicy compiles patterns, which use regexp syntax.
+func NewRedactionPolicy(patterns ...string) (*RedactionPolicy, error) {
+	p := &RedactionPolicy{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %q: %w", pattern, err)
+		}
+		p.Patterns = append(p.Patterns, re)
+	}
+	return p, nil
+}
+
+// redactionExempt are the DockerEnvironment fields that come from the
+// configuration rather than docker; they identify the environment and
+// stay as they are.
+var redactionExempt = map[string]bool{
+	"Environment": true, "Host": true, "SocketPath": true, "ComposeFile": true, "Tags": true,
+}
+
+// redactEnvironment masks the string fields of e in place, so e must not
+// share memory with the live monitor, see Snapshot.
+func (p *RedactionPolicy) redactEnvironment(e *DockerEnvironment) {
+	v := reflect.ValueOf(e).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !redactionExempt[t.Field(i).Name] {
+			p.redact(v.Field(i))
+		}
+	}
+}
+
+// redact masks the strings reachable from v. Maps are left alone: the only
+// ones hold capabilities and the raw JSON of extensions.
+func (p *RedactionPolicy) redact(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(p.redactString(v.String()))
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			p.redact(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			p.redact(v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() && t.Field(i).Tag.Get("json") != "-" {
+				p.redact(v.Field(i))
+			}
+		}
+	}
+}
+
+func (p *RedactionPolicy) redactString(s string) string {
+	for _, re := range p.Patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllLiteralString(s, Redacted)
+			continue
+		}
+		var masked []byte
+		last := 0
+		for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+			// match[2:4] is the first group, -1 if it didn't participate.
+			if match[2] < 0 {
+				continue
+			}
+			masked = append(masked, s[last:match[2]]...)
+			masked = append(masked, Redacted...)
+			last = match[3]
+		}
+		if masked != nil {
+			s = string(append(masked, s[last:]...))
+		}
+	}
+	return s
+}
@@ -0,0 +1,154 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const DefaultRegistryTimeout = 15 * time.Second
+
+// OutdatedImage is a local image whose tag now points at a different digest
+// in its registry.
+type OutdatedImage struct {
+	Image        string `json:"image"`
+	RemoteRef    string `json:"remoteRef"`
+	LocalDigest  string `json:"localDigest"`
+	RemoteDigest string `json:"remoteDigest"`
+}
+
+// CheckImagePullPolicy compares the digest of every pulled image with the
+// digest its registry currently serves. Tag selects the remote tag to compare
+// against, e.g. "latest"; when empty each image is compared with its own tag.
+// Locally built images without a repo digest are skipped.
+type CheckImagePullPolicy struct {
+	dockerMonitor *DockerMonitor
+	Tag           string
+	Timeout       time.Duration
+}
+
+func (c CheckImagePullPolicy) Name() string        { return "image-pull-policy" }
+func (c CheckImagePullPolicy) DependsOn() []string { return []string{"local-images"} }
+
+func (c CheckImagePullPolicy) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, img := range dockerEnv.ImagesInfo {
+		if img.Repository != "<none>" {
+			ids = append(ids, img.ID)
+		}
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .RepoDigests}}")
+	if err != nil {
+		return err
+	}
+
+	// buildx reports the digest of multi-platform manifest lists, which is what
+	// RepoDigests records for images pulled from such a list.
+	useBuildx := c.dockerMonitor.requireCapability(env, CapabilityBuildx) == nil
+
+	var outdated []OutdatedImage
+	for _, img := range dockerEnv.ImagesInfo {
+		raw, found := inspected[img.ID]
+		if !found {
+			continue
+		}
+		localDigest := repoDigest(raw, img.Repository)
+		if localDigest == "" {
+			continue
+		}
+		tag := c.Tag
+		if tag == "" {
+			tag = img.Tag
+		}
+		remoteRef := img.Repository + ":" + tag
+		remoteDigest, err := c.remoteDigest(ctx, env, remoteRef, useBuildx)
+		if environmentDown(err) {
+			return err
+		}
+		if err != nil {
+			c.dockerMonitor.logger().Warn("resolving remote digest", "environment", env, "image", remoteRef, "error", err)
+			continue
+		}
+		if remoteDigest != "" && remoteDigest != localDigest {
+			outdated = append(outdated, OutdatedImage{
+				Image:        img.Repository + ":" + img.Tag,
+				RemoteRef:    remoteRef,
+				LocalDigest:  localDigest,
+				RemoteDigest: remoteDigest,
+			})
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.OutdatedImages = outdated
+	})
+	c.dockerMonitor.logger().Info("outdated images", "environment", env, "outdated", len(outdated))
+	return nil
+}
+
+// repoDigest picks the digest recorded for repository from the RepoDigests inspect JSON.
+func repoDigest(raw, repository string) string {
+	var digests []string
+	if err := json.Unmarshal([]byte(raw), &digests); err != nil {
+		return ""
+	}
+	i := slices.IndexFunc(digests, func(d string) bool {
+		return strings.HasPrefix(d, repository+"@")
+	})
+	if i < 0 {
+		return ""
+	}
+	_, digest, _ := strings.Cut(digests[i], "@")
+	return digest
+}
+
+// remoteDigest resolves the digest ref currently points at in its registry.
+// An empty digest means it can't be compared, e.g. a manifest list without buildx.
+func (c CheckImagePullPolicy) remoteDigest(ctx context.Context, env, ref string, useBuildx bool) (string, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRegistryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	if useBuildx {
+		out, err := c.dockerMonitor.DockerOutput(ctx, env, "buildx", "imagetools", "inspect", ref, "--format", "{{json .Manifest}}")
+		if err != nil {
+			return "", err
+		}
+		var manifest struct {
+			Digest string `json:"digest"`
+		}
+		if err := json.Unmarshal(out, &manifest); err != nil {
+			return "", err
+		}
+		return manifest.Digest, nil
+	}
+
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "manifest", "inspect", "--verbose", ref)
+	if err != nil {
+		return "", err
+	}
+	var single struct {
+		Descriptor struct {
+			Digest string `json:"digest"`
+		}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(out, &single); errors.As(err, &typeErr) {
+		// Manifest lists come back as an array of per-platform manifests,
+		// none of which is the list digest stored locally.
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return single.Descriptor.Digest, nil
+}
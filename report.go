@@ -0,0 +1,158 @@
+package dockermonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// EnvironmentMatrix compares what runs in every environment: a row per
+// service and a cell per environment running it.
+type EnvironmentMatrix struct {
+	Environments []string    `json:"environments"`
+	Rows         []MatrixRow `json:"rows"`
+}
+
+// MatrixRow is a compose service, or the image repository of containers
+// outside compose, across environments.
+type MatrixRow struct {
+	Service string `json:"service"`
+	// Cells are keyed by environment; environments not running the service
+	// have none.
+	Cells map[string]MatrixCell `json:"cells"`
+	// Drift is set when the environments don't all run the same tags and
+	// digests, e.g. when Prod lags Staging.
+	Drift bool `json:"drift"`
+}
+
+// MatrixCell is what an environment runs of a service.
+type MatrixCell struct {
+	Tags    []string `json:"tags"`
+	Digests []string `json:"digests,omitempty"`
+	// Replicas counts the running containers.
+	Replicas int `json:"replicas"`
+}
+
+func (c MatrixCell) String() string {
+	s := strings.Join(c.Tags, ",")
+	for _, digest := range c.Digests {
+		if len(digest) > len("sha256:")+12 {
+			digest = digest[:len("sha256:")+12]
+		}
+		s += "@" + digest
+	}
+	return fmt.Sprintf("%s (%d)", s, c.Replicas)
+}
+
+// CrossEnvironmentReport builds the matrix of the running containers of
+// every environment, once the workflows have run. It only uses the data
+// CheckContainersStatus and CheckLocalImages collected; without local
+// images the cells have no digests.
+func (d *DockerMonitor) CrossEnvironmentReport() EnvironmentMatrix {
+	var matrix EnvironmentMatrix
+	rows := make(map[string]*MatrixRow)
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		matrix.Environments = append(matrix.Environments, dockerEnv.Environment)
+		for _, cont := range dockerEnv.ContainersInfo {
+			if cont.State != "running" {
+				continue
+			}
+			repository, tag := splitImageReference(cont.Image)
+			service := cont.LabelMap()[composeServiceLabel]
+			if service == "" {
+				service = repository
+			}
+			row, found := rows[service]
+			if !found {
+				row = &MatrixRow{Service: service, Cells: make(map[string]MatrixCell)}
+				rows[service] = row
+			}
+			cell := row.Cells[dockerEnv.Environment]
+			cell.Replicas += 1
+			if !slices.Contains(cell.Tags, tag) {
+				cell.Tags = append(cell.Tags, tag)
+				sort.Strings(cell.Tags)
+			}
+			for _, img := range dockerEnv.ImagesInfo {
+				if imageMatchesReference(cont.Image, img) && strings.HasPrefix(img.Digest, "sha256:") && !slices.Contains(cell.Digests, img.Digest) {
+					cell.Digests = append(cell.Digests, img.Digest)
+					sort.Strings(cell.Digests)
+				}
+			}
+			row.Cells[dockerEnv.Environment] = cell
+		}
+	}
+
+	for _, row := range rows {
+		var first string
+		for i, env := range matrix.Environments {
+			cell, found := row.Cells[env]
+			if !found {
+				row.Drift = true
+				break
+			}
+			deployed := strings.Join(cell.Tags, ",") + "@" + strings.Join(cell.Digests, ",")
+			if i == 0 {
+				first = deployed
+			} else if deployed != first {
+				row.Drift = true
+				break
+			}
+		}
+		matrix.Rows = append(matrix.Rows, *row)
+	}
+	sort.Slice(matrix.Rows, func(i, j int) bool { return matrix.Rows[i].Service < matrix.Rows[j].Service })
+	return matrix
+}
+
+// splitImageReference returns the repository and tag, or digest, of an
+// image reference; the tag defaults to latest.
+func splitImageReference(ref string) (repository, tag string) {
+	if repository, digest, found := strings.Cut(ref, "@"); found {
+		return repository, digest
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "latest"
+}
+
+// MatrixFormatter writes CrossEnvironmentReport as a table, with a column
+// per environment, or as JSON.
+type MatrixFormatter struct {
+	JSON    bool
+	Compact bool
+}
+
+func (f MatrixFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	matrix := d.CrossEnvironmentReport()
+	if f.JSON {
+		encoder := json.NewEncoder(w)
+		if !f.Compact {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(matrix)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(append(append([]string{"SERVICE"}, matrix.Environments...), "DRIFT"), "\t"))
+	for _, row := range matrix.Rows {
+		line := []string{row.Service}
+		for _, env := range matrix.Environments {
+			if cell, found := row.Cells[env]; found {
+				line = append(line, cell.String())
+			} else {
+				line = append(line, "-")
+			}
+		}
+		drift := ""
+		if row.Drift {
+			drift = "*"
+		}
+		fmt.Fprintln(tw, strings.Join(append(line, drift), "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,98 @@
+package dockermonitor
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// ResumeState records which (environment, action) pairs succeeded so a re-run
+// can skip them and only retry what failed or never ran. Data collected by a
+// skipped action is not restored; only the bookkeeping is persisted, so the
+// workflow runs a succeeded action again when a pending one depends on it.
+type ResumeState struct {
+	mu   sync.Mutex
+	path string
+
+	// Succeeded maps an environment to the names of its succeeded actions.
+	Succeeded map[string][]string `json:"succeeded"`
+}
+
+// NewResumeState returns an empty state that is saved to path.
+func NewResumeState(path string) *ResumeState {
+	return &ResumeState{path: path, Succeeded: map[string][]string{}}
+}
+
+// LoadResumeState reads the state saved at path. A missing file yields an empty state.
+func LoadResumeState(path string) (*ResumeState, error) {
+	state := NewResumeState(path)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Succeeded == nil {
+		state.Succeeded = map[string][]string{}
+	}
+	return state, nil
+}
+
+// Done reports whether action already succeeded for env.
+func (s *ResumeState) Done(env, action string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Contains(s.Succeeded[env], action)
+}
+
+// MarkDone records a successful action and persists the state right away so
+// progress survives a crash mid-run.
+func (s *ResumeState) MarkDone(env, action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(s.Succeeded[env], action) {
+		s.Succeeded[env] = append(s.Succeeded[env], action)
+	}
+	return s.save()
+}
+
+// Clear removes the saved state, e.g. once every workflow completed.
+func (s *ResumeState) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Succeeded = map[string][]string{}
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *ResumeState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so an interrupted save can't corrupt the state.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".resume-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
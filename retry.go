@@ -0,0 +1,63 @@
+package dockermonitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// retryBackoff is the wait before the first retry of an action; it doubles
+// with every further attempt.
+const retryBackoff = time.Second
+
+// RetryBudget is a token bucket bounding how many retries all workflows of a
+// monitor make together. During a widespread outage, such as an unreachable
+// registry, it turns a storm of retries into fast failures once drained.
+type RetryBudget struct {
+	mu        sync.Mutex
+	perMinute float64
+	tokens    float64
+	refilled  time.Time
+}
+
+// NewRetryBudget allows bursts of up to perMinute retries, refilled at
+// perMinute retries a minute.
+func NewRetryBudget(perMinute int) *RetryBudget {
+	return &RetryBudget{perMinute: float64(perMinute), tokens: float64(perMinute), refilled: time.Now()}
+}
+
+// allow takes a token if one is left. A nil budget allows every retry.
+func (b *RetryBudget) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.perMinute, b.tokens+now.Sub(b.refilled).Minutes()*b.perMinute)
+	b.refilled = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryable reports whether a failed action is worth running again: skips
+// and cancellations won't go away by retrying.
+func retryable(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && !errors.Is(err, ErrUnsupported)
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,207 @@
+package dockermonitor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// inspectedContainer is the part of `docker inspect` a run command is rebuilt from.
+type inspectedContainer struct {
+	Name   string
+	Image  string
+	Config struct {
+		containerConfig
+		Image string
+	}
+	HostConfig struct {
+		NetworkMode   string
+		Privileged    bool
+		AutoRemove    bool
+		RestartPolicy struct {
+			Name              string
+			MaximumRetryCount int
+		}
+		PortBindings map[string][]struct {
+			HostIp   string
+			HostPort string
+		}
+	}
+	Mounts []struct {
+		Type        string
+		Name        string
+		Source      string
+		Destination string
+		RW          bool
+	}
+}
+
+type CheckContainerCreateArgs struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerCreateArgs) Name() string        { return "container-run-command" }
+func (c CheckContainerCreateArgs) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerCreateArgs) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .}}")
+	if err != nil {
+		return err
+	}
+
+	containers := make(map[string]inspectedContainer)
+	var imageIDs []string
+	for id, raw := range inspected {
+		var cont inspectedContainer
+		if err := unmarshalLine(raw, "inspectedContainer", &cont); err != nil {
+			return err
+		}
+		containers[id] = cont
+		if !slices.Contains(imageIDs, cont.Image) {
+			imageIDs = append(imageIDs, cont.Image)
+		}
+	}
+
+	// Settings equal to the image defaults are left out of the command.
+	inspectedImages, err := c.dockerMonitor.inspect(ctx, env, imageIDs, "{{json .Config}}")
+	if err != nil {
+		return err
+	}
+
+	commands := make(map[string]string)
+	for id, cont := range containers {
+		var image containerConfig
+		if raw, found := inspectedImages[cont.Image]; found {
+			if err := unmarshalLine(raw, "containerConfig", &image); err != nil {
+				return err
+			}
+		}
+		commands[id] = runCommand(cont, image)
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			if command, found := commands[e.ContainersInfo[i].ID]; found {
+				e.ContainersInfo[i].RunCommand = command
+			}
+		}
+	})
+	c.dockerMonitor.logger().Info("container run commands", "environment", env, "containers", len(commands))
+	return nil
+}
+
+// runCommand rebuilds an approximate `docker run` invocation. Environment
+// variables are passed by name only (`-e KEY`) so their values, which may be
+// secrets, never end up in the collected data.
+func runCommand(cont inspectedContainer, image containerConfig) string {
+	args := []string{"docker", "run", "-d"}
+	if name := strings.TrimPrefix(cont.Name, "/"); name != "" {
+		args = append(args, "--name", name)
+	}
+	if cont.HostConfig.AutoRemove {
+		args = append(args, "--rm")
+	}
+	if policy := cont.HostConfig.RestartPolicy; policy.Name != "" && policy.Name != "no" {
+		if policy.Name == "on-failure" && policy.MaximumRetryCount > 0 {
+			args = append(args, "--restart", fmt.Sprintf("on-failure:%d", policy.MaximumRetryCount))
+		} else {
+			args = append(args, "--restart", policy.Name)
+		}
+	}
+	if mode := cont.HostConfig.NetworkMode; mode != "" && mode != "default" && mode != "bridge" {
+		args = append(args, "--network", mode)
+	}
+	if cont.HostConfig.Privileged {
+		args = append(args, "--privileged")
+	}
+
+	var ports []string
+	for port := range cont.HostConfig.PortBindings {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		containerPort := strings.TrimSuffix(port, "/tcp")
+		for _, binding := range cont.HostConfig.PortBindings[port] {
+			spec := containerPort
+			if binding.HostPort != "" {
+				spec = binding.HostPort + ":" + spec
+			}
+			if binding.HostIp != "" && binding.HostIp != "0.0.0.0" && binding.HostIp != "::" {
+				spec = binding.HostIp + ":" + spec
+			}
+			args = append(args, "-p", spec)
+		}
+	}
+
+	for _, m := range cont.Mounts {
+		var spec string
+		switch {
+		case m.Type == "tmpfs":
+			args = append(args, "--tmpfs", m.Destination)
+			continue
+		case m.Type == "bind":
+			spec = m.Source + ":" + m.Destination
+		case m.Type == "volume" && isImageID(m.Name):
+			// Anonymous volumes are recreated by naming just the path.
+			spec = m.Destination
+		default:
+			spec = m.Name + ":" + m.Destination
+		}
+		if !m.RW {
+			spec += ":ro"
+		}
+		args = append(args, "-v", spec)
+	}
+
+	for _, kv := range cont.Config.Env {
+		if !slices.Contains(image.Env, kv) {
+			key, _, _ := strings.Cut(kv, "=")
+			args = append(args, "-e", key)
+		}
+	}
+	if cont.Config.User != image.User && cont.Config.User != "" {
+		args = append(args, "--user", cont.Config.User)
+	}
+	if cont.Config.WorkingDir != image.WorkingDir && cont.Config.WorkingDir != "" {
+		args = append(args, "--workdir", cont.Config.WorkingDir)
+	}
+
+	// docker run only takes the entrypoint's first element; the rest joins the command.
+	entrypointChanged := !slices.Equal(cont.Config.Entrypoint, image.Entrypoint)
+	var command []string
+	if entrypointChanged {
+		entrypoint := ""
+		if len(cont.Config.Entrypoint) > 0 {
+			entrypoint = cont.Config.Entrypoint[0]
+			command = append(command, cont.Config.Entrypoint[1:]...)
+		}
+		args = append(args, "--entrypoint", entrypoint)
+	}
+	if entrypointChanged || !slices.Equal(cont.Config.Cmd, image.Cmd) {
+		command = append(command, cont.Config.Cmd...)
+	}
+
+	args = append(args, cont.Config.Image)
+	args = append(args, command...)
+	for i, arg := range args {
+		args[i] = shellWord(arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// shellWord quotes s only when a POSIX shell would otherwise split or expand it.
+func shellWord(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,:/=@%+") == "" {
+		return s
+	}
+	return shellQuote(s)
+}
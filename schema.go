@@ -0,0 +1,52 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+//go:embed schema/snapshot.schema.json
+var schemaFS embed.FS
+
+const snapshotSchemaPath = "schema/snapshot.schema.json"
+
+// SnapshotSchema returns the JSON schema ValidateSnapshot checks against. It
+// describes the output of JSONFormatter with the default key case and no
+// field selection; fields may be added in later versions, but not removed
+// or retyped.
+func SnapshotSchema() []byte {
+	data, _ := schemaFS.ReadFile(snapshotSchemaPath)
+	return data
+}
+
+var compileSnapshotSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat = true
+	if err := compiler.AddResource(snapshotSchemaPath, bytes.NewReader(SnapshotSchema())); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(snapshotSchemaPath)
+})
+
+// ValidateSnapshot checks that data, a JSON document written by
+// JSONFormatter, matches SnapshotSchema. Consumers can use it in their tests
+// to catch output changes when upgrading the package.
+func ValidateSnapshot(data []byte) error {
+	schema, err := compileSnapshotSchema()
+	if err != nil {
+		return fmt.Errorf("compiling snapshot schema: %w", err)
+	}
+	var snapshot any
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if err := schema.Validate(snapshot); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package dockermonitor
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// SecurityFinding flags a container with a weaker security posture. Check
+// names the action's rule, e.g. "root-user" or "writable-rootfs".
+type SecurityFinding struct {
+	Container string `json:"container"`
+	Check     string `json:"check"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// setSecurityFindings replaces the findings of check, so running an action
+// again doesn't duplicate them.
+func (e *DockerEnvironment) setSecurityFindings(check string, findings []SecurityFinding) {
+	e.SecurityFindings = slices.DeleteFunc(e.SecurityFindings, func(f SecurityFinding) bool {
+		return f.Check == check
+	})
+	e.SecurityFindings = append(e.SecurityFindings, findings...)
+}
+
+// runsAsRoot reports whether a container's Config.User means UID 0. An empty
+// user falls back to the image default, which is root.
+func runsAsRoot(user string) bool {
+	name, _, _ := strings.Cut(strings.TrimSpace(user), ":")
+	return name == "" || name == "0" || name == "root"
+}
+
+type CheckContainerUser struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerUser) Name() string        { return "container-user" }
+func (c CheckContainerUser) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records the configured user of every container and lists the
+// running ones that run as root in RootContainers.
+func (c CheckContainerUser) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.Config.User}}")
+	if err != nil {
+		return err
+	}
+
+	var root []string
+	var findings []SecurityFinding
+	for _, cont := range dockerEnv.ContainersInfo {
+		user, found := inspected[cont.ID]
+		if found && cont.State == "running" && runsAsRoot(user) {
+			root = append(root, cont.Names)
+			findings = append(findings, SecurityFinding{Container: cont.Names, Check: "root-user", Detail: "runs as root"})
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].User = inspected[e.ContainersInfo[i].ID]
+		}
+		e.RootContainers = root
+		e.setSecurityFindings("root-user", findings)
+	})
+	c.dockerMonitor.logger().Info("container users", "environment", env, "root", len(root))
+	return nil
+}
+
+type CheckContainerReadOnlyRootfs struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerReadOnlyRootfs) Name() string        { return "container-readonly-rootfs" }
+func (c CheckContainerReadOnlyRootfs) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute lists the running containers whose root filesystem is writable.
+func (c CheckContainerReadOnlyRootfs) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.HostConfig.ReadonlyRootfs}}")
+	if err != nil {
+		return err
+	}
+
+	var writable []string
+	var findings []SecurityFinding
+	for _, cont := range dockerEnv.ContainersInfo {
+		readonly, found := inspected[cont.ID]
+		if found && cont.State == "running" && strings.TrimSpace(readonly) != "true" {
+			writable = append(writable, cont.Names)
+			findings = append(findings, SecurityFinding{Container: cont.Names, Check: "writable-rootfs", Detail: "root filesystem is writable"})
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.WritableRootfsContainers = writable
+		e.setSecurityFindings("writable-rootfs", findings)
+	})
+	c.dockerMonitor.logger().Info("container root filesystems", "environment", env, "writable", len(writable))
+	return nil
+}
+
+// dangerousCapabilities are the Linux capabilities that let a container
+// escape or take over the host, or snoop on other processes.
+var dangerousCapabilities = []string{"ALL", "SYS_ADMIN", "NET_ADMIN", "SYS_PTRACE", "SYS_MODULE", "SYS_RAWIO", "DAC_READ_SEARCH", "BPF"}
+
+type CheckContainerCapabilities struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerCapabilities) Name() string        { return "container-capabilities" }
+func (c CheckContainerCapabilities) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records the capabilities every container adds and drops, and
+// reports running containers adding a dangerous one.
+func (c CheckContainerCapabilities) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, `{"add":{{json .HostConfig.CapAdd}},"drop":{{json .HostConfig.CapDrop}}}`)
+	if err != nil {
+		return err
+	}
+
+	type capabilities struct {
+		Add, Drop []string
+	}
+	caps := make(map[string]capabilities)
+	var findings []SecurityFinding
+	for _, cont := range dockerEnv.ContainersInfo {
+		raw, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		var cc capabilities
+		if err := unmarshalLine(raw, "capabilities", &cc); err != nil {
+			return err
+		}
+		// Docker accepts "sys_admin" and "CAP_SYS_ADMIN" alike.
+		for i, capability := range cc.Add {
+			cc.Add[i] = strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+		}
+		for i, capability := range cc.Drop {
+			cc.Drop[i] = strings.TrimPrefix(strings.ToUpper(capability), "CAP_")
+		}
+		caps[cont.ID] = cc
+
+		var dangerous []string
+		for _, capability := range cc.Add {
+			if slices.Contains(dangerousCapabilities, capability) && !slices.Contains(cc.Drop, capability) {
+				dangerous = append(dangerous, capability)
+			}
+		}
+		if len(dangerous) > 0 && cont.State == "running" {
+			findings = append(findings, SecurityFinding{Container: cont.Names, Check: "dangerous-capability", Detail: "adds " + strings.Join(dangerous, ", ")})
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			cc := caps[e.ContainersInfo[i].ID]
+			e.ContainersInfo[i].CapAdd = cc.Add
+			e.ContainersInfo[i].CapDrop = cc.Drop
+		}
+		e.setSecurityFindings("dangerous-capability", findings)
+	})
+	c.dockerMonitor.logger().Info("container capabilities", "environment", env, "dangerous", len(findings))
+	return nil
+}
+
+type CheckContainerSecurityOpt struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerSecurityOpt) Name() string        { return "container-security-opt" }
+func (c CheckContainerSecurityOpt) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records every container's security options and AppArmor profile,
+// and reports running containers with seccomp disabled, or without an
+// AppArmor profile when the daemon enforces AppArmor.
+func (c CheckContainerSecurityOpt) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "info", "--format", "{{json .SecurityOptions}}")
+	if err != nil {
+		return err
+	}
+	appArmor := strings.Contains(string(out), "name=apparmor")
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, `{"securityOpt":{{json .HostConfig.SecurityOpt}},"appArmorProfile":{{json .AppArmorProfile}}}`)
+	if err != nil {
+		return err
+	}
+
+	type securityOpt struct {
+		SecurityOpt     []string
+		AppArmorProfile string
+	}
+	opts := make(map[string]securityOpt)
+	var seccomp, appArmorFindings []SecurityFinding
+	for _, cont := range dockerEnv.ContainersInfo {
+		raw, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		var so securityOpt
+		if err := unmarshalLine(raw, "securityOpt", &so); err != nil {
+			return err
+		}
+		opts[cont.ID] = so
+		if cont.State != "running" {
+			continue
+		}
+		// Docker before 17.05 separated options with a colon.
+		for _, opt := range so.SecurityOpt {
+			if opt == "seccomp=unconfined" || opt == "seccomp:unconfined" {
+				seccomp = append(seccomp, SecurityFinding{Container: cont.Names, Check: "seccomp-unconfined", Detail: "seccomp filtering is disabled"})
+			}
+		}
+		if appArmor && (so.AppArmorProfile == "" || so.AppArmorProfile == "unconfined") {
+			appArmorFindings = append(appArmorFindings, SecurityFinding{Container: cont.Names, Check: "apparmor-unconfined", Detail: "no AppArmor profile"})
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			so := opts[e.ContainersInfo[i].ID]
+			e.ContainersInfo[i].SecurityOpt = so.SecurityOpt
+			e.ContainersInfo[i].AppArmorProfile = so.AppArmorProfile
+		}
+		e.setSecurityFindings("seccomp-unconfined", seccomp)
+		e.setSecurityFindings("apparmor-unconfined", appArmorFindings)
+	})
+	c.dockerMonitor.logger().Info("container security options", "environment", env, "seccompUnconfined", len(seccomp), "appArmorUnconfined", len(appArmorFindings))
+	return nil
+}
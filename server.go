@@ -0,0 +1,222 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Server exposes the monitor's collected state over HTTP. Every request reads
+// from a Snapshot, so handlers never race with running workflows.
+type Server struct {
+	monitor *DockerMonitor
+	mux     *http.ServeMux
+	metrics *PrometheusExporter
+
+	// Refresh, if set, enables POST /refresh. It is called to collect fresh
+	// data for envs, or for every environment when envs is empty, typically
+	// by running the workflows again, before the snapshot is returned.
+	Refresh func(ctx context.Context, envs ...string) error
+
+	// refreshing is held while Refresh runs; overlapping POST /refresh
+	// requests get a 429.
+	refreshing sync.Mutex
+	// refreshedAt is when each environment was last refreshed, guarded by
+	// refreshing; see WithCacheTTL.
+	refreshedAt map[string]time.Time
+	cacheTTL    time.Duration
+
+	// clients has a notification channel per connected WebSocket client.
+	clientsMu sync.Mutex
+	clients   map[chan struct{}]struct{}
+}
+
+// RefreshResult is the response to POST /refresh.
+type RefreshResult struct {
+	CollectedAt  time.Time           `json:"collectedAt"`
+	Environments []DockerEnvironment `json:"environments"`
+	Error        string              `json:"error,omitempty"`
+}
+
+// ServerOption configures a Server in NewServer.
+type ServerOption func(*Server)
+
+// WithCacheTTL makes reads of environments refreshed longer than ttl ago run
+// Refresh for them first, so a dashboard polling the API gets data at most
+// ttl old without every request running docker. POST /refresh always
+// refreshes. Without this option only POST /refresh collects data.
+func WithCacheTTL(ttl time.Duration) ServerOption {
+	return func(s *Server) {
+		s.cacheTTL = ttl
+	}
+}
+
+// NewServer registers the JSON API:
+//
+//	GET /environments                          names of all environments
+//	GET /environments/{name}                   one environment's full data
+//	GET /environments/{name}/containers        just its containers
+//	POST /refresh                              collect now, see Server.Refresh
+//	GET /metrics                               Prometheus gauges and counters
+//	GET /ws                                    WebSocket of LiveUpdate messages
+//
+// The monitor should hold collected data already; it is the baseline the
+// start and stop counters of later refreshes are counted from.
+func NewServer(d *DockerMonitor, opts ...ServerOption) *Server {
+	s := &Server{monitor: d, mux: http.NewServeMux(), metrics: NewPrometheusExporter(d), refreshedAt: make(map[string]time.Time)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.metrics.Observe()
+	s.markRefreshed(time.Now())
+	s.mux.HandleFunc("GET /environments", s.listEnvironments)
+	s.mux.HandleFunc("GET /environments/{name}", s.getEnvironment)
+	s.mux.HandleFunc("GET /environments/{name}/containers", s.getContainers)
+	s.mux.HandleFunc("POST /refresh", s.refresh)
+	s.mux.HandleFunc("GET /metrics", s.getMetrics)
+	s.mux.HandleFunc("GET /ws", s.streamUpdates)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) listEnvironments(w http.ResponseWriter, r *http.Request) {
+	names := []string{}
+	for _, dockerEnv := range s.monitor.Snapshot().DockerEnvironments {
+		names = append(names, dockerEnv.Environment)
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) getEnvironment(w http.ResponseWriter, r *http.Request) {
+	s.refreshStale(r.Context(), r.PathValue("name"))
+	dockerEnv, found := s.environment(r.PathValue("name"))
+	if !found {
+		writeJSONError(w, http.StatusNotFound, "unknown environment "+r.PathValue("name"))
+		return
+	}
+	writeJSON(w, http.StatusOK, dockerEnv)
+}
+
+func (s *Server) getContainers(w http.ResponseWriter, r *http.Request) {
+	s.refreshStale(r.Context(), r.PathValue("name"))
+	dockerEnv, found := s.environment(r.PathValue("name"))
+	if !found {
+		writeJSONError(w, http.StatusNotFound, "unknown environment "+r.PathValue("name"))
+		return
+	}
+	writeJSON(w, http.StatusOK, dockerEnv.ContainersInfo)
+}
+
+// environment returns the named environment with the monitor's Redaction
+// applied, like Snapshot does; handlers never serve Environment directly.
+func (s *Server) environment(name string) (DockerEnvironment, bool) {
+	dockerEnv, found := s.monitor.Environment(name)
+	if found && s.monitor.Redaction != nil {
+		s.monitor.Redaction.redactEnvironment(&dockerEnv)
+	}
+	return dockerEnv, found
+}
+
+func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
+	if s.Refresh == nil {
+		writeJSONError(w, http.StatusNotImplemented, "refresh is not enabled")
+		return
+	}
+	if !s.refreshing.TryLock() {
+		writeJSONError(w, http.StatusTooManyRequests, "a refresh is already running")
+		return
+	}
+	defer s.refreshing.Unlock()
+
+	// Finish the run even if the client hangs up, so environments aren't left
+	// half updated.
+	startedAt := time.Now()
+	err := s.Refresh(context.WithoutCancel(r.Context()))
+	s.markRefreshed(startedAt)
+	s.metrics.Observe()
+	s.Notify()
+	result := RefreshResult{
+		CollectedAt:  time.Now().UTC(),
+		Environments: s.monitor.Snapshot().DockerEnvironments,
+	}
+	status := http.StatusOK
+	if err != nil {
+		result.Error = err.Error()
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, result)
+}
+
+// refreshStale runs Refresh for the environments, or all of them when
+// none are given, that were refreshed longer than the cache TTL ago. Reads
+// carry on with the data they have if that fails.
+func (s *Server) refreshStale(ctx context.Context, envs ...string) {
+	if s.cacheTTL <= 0 || s.Refresh == nil {
+		return
+	}
+	// Waiting for a running refresh is better than a second one right after.
+	s.refreshing.Lock()
+	defer s.refreshing.Unlock()
+
+	if len(envs) == 0 {
+		envs = s.environmentNames()
+	}
+	var stale []string
+	for _, env := range envs {
+		if _, known := s.monitor.Environment(env); known && time.Since(s.refreshedAt[env]) > s.cacheTTL {
+			stale = append(stale, env)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	startedAt := time.Now()
+	err := s.Refresh(context.WithoutCancel(ctx), stale...)
+	s.markRefreshed(startedAt, stale...)
+	s.metrics.Observe()
+	s.Notify()
+	if err != nil {
+		s.monitor.logger().Warn("refreshing stale environments", "environments", stale, "error", err)
+	}
+}
+
+// markRefreshed records envs, or every environment, as refreshed at t.
+// Callers hold refreshing, except NewServer.
+func (s *Server) markRefreshed(t time.Time, envs ...string) {
+	if len(envs) == 0 {
+		envs = s.environmentNames()
+	}
+	for _, env := range envs {
+		s.refreshedAt[env] = t
+	}
+}
+
+func (s *Server) environmentNames() []string {
+	var names []string
+	for _, dockerEnv := range s.monitor.Snapshot().DockerEnvironments {
+		names = append(names, dockerEnv.Environment)
+	}
+	return names
+}
+
+func (s *Server) getMetrics(w http.ResponseWriter, r *http.Request) {
+	s.refreshStale(r.Context())
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.WritePrometheusText(w)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,167 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Signature statuses set on ImageInfo.SignatureStatus.
+const (
+	SignatureSigned   = "signed"
+	SignatureUnsigned = "unsigned"
+	SignatureInvalid  = "invalid"
+	// SignatureUnknown is used for untagged images and when verification
+	// failed for another reason, such as an unreachable registry.
+	SignatureUnknown = "unknown"
+)
+
+// DefaultProductionTag is the environment tag CheckImageSignatures treats as
+// production when ProductionTag is empty.
+const DefaultProductionTag = "production"
+
+type CheckImageSignatures struct {
+	dockerMonitor *DockerMonitor
+	// CosignKey, if set, verifies images with `cosign verify --key`, which
+	// must be installed where the monitor runs. Otherwise Docker Content Trust
+	// signatures are checked with `docker trust inspect`.
+	CosignKey string
+	// ProductionTag marks the environments, by their config tags, whose
+	// running containers must use signed images; DefaultProductionTag when empty.
+	ProductionTag string
+}
+
+func (c CheckImageSignatures) Name() string { return "image-signatures" }
+func (c CheckImageSignatures) DependsOn() []string {
+	return []string{"containers-status", "local-images"}
+}
+
+// Execute sets SignatureStatus on every tagged local image and, in production
+// environments, lists the images running containers use that aren't signed.
+func (c CheckImageSignatures) Execute(ctx context.Context, env string) error {
+	if c.CosignKey != "" {
+		if _, err := exec.LookPath("cosign"); err != nil {
+			return unsupported("cosign is not installed")
+		}
+	}
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	statuses := make(map[string]string)
+	for _, img := range dockerEnv.ImagesInfo {
+		if img.Repository == "<none>" || img.Tag == "<none>" {
+			statuses[img.ID] = SignatureUnknown
+			continue
+		}
+		ref := img.Repository + ":" + img.Tag
+		var status string
+		var err error
+		if c.CosignKey != "" {
+			status, err = c.verifyCosign(ctx, ref)
+		} else {
+			status, err = c.inspectTrust(ctx, env, ref, img.Digest)
+		}
+		if errors.Is(err, ErrUnsupported) || environmentDown(err) {
+			return err
+		}
+		if err != nil {
+			c.dockerMonitor.logger().Warn("verifying image signature", "environment", env, "image", ref, "error", err)
+		}
+		statuses[img.ID] = status
+	}
+
+	productionTag := c.ProductionTag
+	if productionTag == "" {
+		productionTag = DefaultProductionTag
+	}
+	var unsigned []string
+	if slices.Contains(dockerEnv.Tags, productionTag) {
+		for _, img := range dockerEnv.ImagesInfo {
+			if status := statuses[img.ID]; status != SignatureSigned && status != SignatureUnknown && dockerEnv.runningFrom(img) {
+				unsigned = append(unsigned, img.Repository+":"+img.Tag)
+			}
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ImagesInfo {
+			if status, ok := statuses[e.ImagesInfo[i].ID]; ok {
+				e.ImagesInfo[i].SignatureStatus = status
+			}
+		}
+		e.UnsignedProductionImages = unsigned
+	})
+	c.dockerMonitor.logger().Info("image signatures", "environment", env, "unsignedInProduction", len(unsigned))
+	return nil
+}
+
+// verifyCosign runs cosign, which talks to the registry itself and so always
+// runs locally, even for remote environments.
+func (c CheckImageSignatures) verifyCosign(ctx context.Context, ref string) (string, error) {
+	if timeout := c.dockerMonitor.commandTimeout([]string{"cosign"}); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	cmd := exec.CommandContext(ctx, "cosign", "verify", "--key", c.CosignKey, ref)
+	out, err := cmd.CombinedOutput()
+	switch {
+	case err == nil:
+		return SignatureSigned, nil
+	case strings.Contains(string(out), "no signatures found"):
+		return SignatureUnsigned, nil
+	case strings.Contains(string(out), "no matching signatures"), strings.Contains(string(out), "invalid signature"):
+		return SignatureInvalid, nil
+	}
+	return SignatureUnknown, commandError(cmd, errors.New(strings.TrimSpace(string(out))))
+}
+
+// inspectTrust looks ref's tag up in its Docker Content Trust data. A signed
+// tag whose digest differs from the local image's is invalid.
+func (c CheckImageSignatures) inspectTrust(ctx context.Context, env, ref, digest string) (string, error) {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	cmd, release := c.dockerMonitor.DockerCommand(ctx, env, "trust", "inspect", ref)
+	defer release()
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		switch message := stderr.String(); {
+		case strings.Contains(message, "unknown command"), strings.Contains(message, "is not a docker command"):
+			return SignatureUnknown, unsupported("docker trust is not available")
+		case strings.Contains(strings.ToLower(message), "no signatures"), strings.Contains(message, "does not have trust data"):
+			return SignatureUnsigned, nil
+		}
+		return SignatureUnknown, commandErrorWithStderr(cmd, err, []byte(stderr.String()))
+	}
+
+	var repositories []struct {
+		SignedTags []struct {
+			SignedTag string
+			Digest    string
+		}
+	}
+	if err := json.Unmarshal(out, &repositories); err != nil {
+		return SignatureUnknown, err
+	}
+	_, tag, _ := strings.Cut(ref[strings.LastIndex(ref, "/")+1:], ":")
+	for _, repository := range repositories {
+		for _, signed := range repository.SignedTags {
+			if signed.SignedTag != tag {
+				continue
+			}
+			if strings.HasPrefix(digest, "sha256:") && "sha256:"+signed.Digest != digest {
+				return SignatureInvalid, nil
+			}
+			return SignatureSigned, nil
+		}
+	}
+	return SignatureUnsigned, nil
+}
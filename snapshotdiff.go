@@ -0,0 +1,162 @@
+package dockermonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Kinds of SnapshotChange.Change.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// SnapshotChange is one difference between two snapshots. Kind is
+// "environment", "container" or "image"; Field, Before and After are only
+// set for changed containers and environments.
+type SnapshotChange struct {
+	Environment string `json:"environment"`
+	Kind        string `json:"kind"`
+	Name        string `json:"name,omitempty"`
+	Change      string `json:"change"`
+	Field       string `json:"field,omitempty"`
+	Before      string `json:"before,omitempty"`
+	After       string `json:"after,omitempty"`
+}
+
+func (c SnapshotChange) String() string {
+	subject := c.Environment
+	if c.Kind != "environment" {
+		subject += ": " + c.Kind + " " + c.Name
+	}
+	if c.Change != ChangeChanged {
+		return subject + " " + c.Change
+	}
+	return fmt.Sprintf("%s: %s %s -> %s", subject, c.Field, c.Before, c.After)
+}
+
+// LoadSnapshot reads a snapshot JSONFormatter wrote to path, with the
+// default key case and every field, after checking it with ValidateSnapshot.
+// Gzip-compressed snapshots, such as SnapshotStore's, are decompressed.
+func LoadSnapshot(path string) ([]DockerEnvironment, error) {
+	data, err := readSnapshotFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateSnapshot(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var environments []DockerEnvironment
+	if err := json.Unmarshal(data, &environments); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return environments, nil
+}
+
+// DiffSnapshots lists what changed from before to after: environments,
+// containers (by name) and images (by repository:tag) that were added or
+// removed, containers whose image or state changed, and changed container
+// and image counts. Changes are sorted by environment, then as listed here.
+func DiffSnapshots(before, after []DockerEnvironment) []SnapshotChange {
+	old := make(map[string]DockerEnvironment)
+	for _, e := range before {
+		old[e.Environment] = e
+	}
+	var changes []SnapshotChange
+	seen := make(map[string]bool)
+	for _, e := range after {
+		seen[e.Environment] = true
+		previous, found := old[e.Environment]
+		if !found {
+			changes = append(changes, SnapshotChange{Environment: e.Environment, Kind: "environment", Change: ChangeAdded})
+			continue
+		}
+		changes = append(changes, diffEnvironment(previous, e)...)
+	}
+	for _, e := range before {
+		if !seen[e.Environment] {
+			changes = append(changes, SnapshotChange{Environment: e.Environment, Kind: "environment", Change: ChangeRemoved})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Environment < changes[j].Environment })
+	return changes
+}
+
+func diffEnvironment(before, after DockerEnvironment) []SnapshotChange {
+	env := after.Environment
+	var changes []SnapshotChange
+	for _, count := range []struct {
+		field         string
+		before, after int
+	}{
+		{"running", before.RunningContainers, after.RunningContainers},
+		{"stopped", before.StoppedContainers, after.StoppedContainers},
+		{"images", before.TotalLocalDockerImages, after.TotalLocalDockerImages},
+	} {
+		if count.before != count.after {
+			changes = append(changes, SnapshotChange{Environment: env, Kind: "environment", Change: ChangeChanged,
+				Field: count.field, Before: fmt.Sprint(count.before), After: fmt.Sprint(count.after)})
+		}
+	}
+
+	oldContainers := make(map[string]ContainerInfo)
+	for _, cont := range before.ContainersInfo {
+		oldContainers[cont.Names] = cont
+	}
+	newContainers := make(map[string]ContainerInfo)
+	for _, cont := range after.ContainersInfo {
+		newContainers[cont.Names] = cont
+	}
+	for _, name := range sortedKeys(newContainers) {
+		cont := newContainers[name]
+		previous, found := oldContainers[name]
+		if !found {
+			changes = append(changes, SnapshotChange{Environment: env, Kind: "container", Name: name, Change: ChangeAdded})
+			continue
+		}
+		for _, field := range []struct{ name, before, after string }{
+			{"image", previous.Image, cont.Image},
+			{"state", previous.State, cont.State},
+		} {
+			if field.before != field.after {
+				changes = append(changes, SnapshotChange{Environment: env, Kind: "container", Name: name, Change: ChangeChanged,
+					Field: field.name, Before: field.before, After: field.after})
+			}
+		}
+	}
+	for _, name := range sortedKeys(oldContainers) {
+		if _, found := newContainers[name]; !found {
+			changes = append(changes, SnapshotChange{Environment: env, Kind: "container", Name: name, Change: ChangeRemoved})
+		}
+	}
+
+	oldImages := imageReferences(before.ImagesInfo)
+	newImages := imageReferences(after.ImagesInfo)
+	for _, ref := range sortedKeys(newImages) {
+		if !oldImages[ref] {
+			changes = append(changes, SnapshotChange{Environment: env, Kind: "image", Name: ref, Change: ChangeAdded})
+		}
+	}
+	for _, ref := range sortedKeys(oldImages) {
+		if !newImages[ref] {
+			changes = append(changes, SnapshotChange{Environment: env, Kind: "image", Name: ref, Change: ChangeRemoved})
+		}
+	}
+	return changes
+}
+
+// imageReferences returns the repository:tag, or the ID for untagged
+// images, of every image.
+func imageReferences(images []ImageInfo) map[string]bool {
+	refs := make(map[string]bool)
+	for _, img := range images {
+		if img.Repository == "<none>" || img.Tag == "<none>" {
+			refs[img.ID] = true
+		} else {
+			refs[img.Repository+":"+img.Tag] = true
+		}
+	}
+	return refs
+}
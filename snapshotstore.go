@@ -0,0 +1,103 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// snapshotTimeLayout names snapshot files so they sort chronologically.
+const snapshotTimeLayout = "20060102T150405.000Z"
+
+// SnapshotStore keeps a history of snapshots in Dir, one gzip-compressed
+// JSON file per Save named after the time it was taken, e.g.
+// snapshot-20240101T100000.000Z.json.gz. Uncompressed .json files from
+// elsewhere can be placed alongside them and are read as well.
+type SnapshotStore struct {
+	Dir string
+}
+
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{Dir: dir}
+}
+
+// Save writes a snapshot of d to the store and returns its path.
+func (s *SnapshotStore) Save(d *DockerMonitor) (string, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.Dir, "snapshot-"+time.Now().UTC().Format(snapshotTimeLayout)+".json.gz")
+	// Written under a temporary name so LoadLatest never sees a partial file.
+	tmp, err := os.CreateTemp(s.Dir, ".snapshot-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	zw := gzip.NewWriter(tmp)
+	if err := (JSONFormatter{Compact: true}).Format(zw, d); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp.Name(), path)
+}
+
+// LoadLatest loads the most recent snapshot in the store, compressed or not.
+// It returns an error wrapping fs.ErrNotExist when there is none.
+func (s *SnapshotStore) LoadLatest() ([]DockerEnvironment, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	latest, latestTime := "", ""
+	for _, entry := range entries {
+		name := entry.Name()
+		taken, found := strings.CutPrefix(name, "snapshot-")
+		if !found || entry.IsDir() {
+			continue
+		}
+		taken, compressed := strings.CutSuffix(taken, ".json.gz")
+		if !compressed {
+			if taken, found = strings.CutSuffix(taken, ".json"); !found {
+				continue
+			}
+		}
+		if taken > latestTime {
+			latest, latestTime = name, taken
+		}
+	}
+	if latest == "" {
+		return nil, fmt.Errorf("no snapshots in %s: %w", s.Dir, fs.ErrNotExist)
+	}
+	return LoadSnapshot(filepath.Join(s.Dir, latest))
+}
+
+// readSnapshotFile reads path, decompressing gzip files whatever their name.
+func readSnapshotFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return data, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
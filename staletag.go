@@ -0,0 +1,67 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+)
+
+// StaleTagContainer is a container created from a tag that has since moved
+// to another image, so it still runs the old one.
+type StaleTagContainer struct {
+	Container string `json:"container"`
+	Image     string `json:"image"`
+	// RunningID is the image the container runs, CurrentID the one Image
+	// points at now; both are short IDs.
+	RunningID string `json:"runningID"`
+	CurrentID string `json:"currentID"`
+}
+
+type CheckContainerImageMismatch struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerImageMismatch) Name() string { return "container-image-mismatch" }
+func (c CheckContainerImageMismatch) DependsOn() []string {
+	return []string{"containers-status", "local-images"}
+}
+
+// Execute compares the image every container runs with the local image its
+// tag points at now, which catches "deployed, but still on the old version"
+// after a tag like myapp:latest was pulled or rebuilt without recreating the
+// container. Containers created from a digest or an image ID can't go stale.
+func (c CheckContainerImageMismatch) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.Image}} {{.Config.Image}}")
+	if err != nil {
+		return err
+	}
+
+	var stale []StaleTagContainer
+	for _, cont := range dockerEnv.ContainersInfo {
+		imageID, ref, found := strings.Cut(strings.TrimSpace(inspected[cont.ID]), " ")
+		imageID = strings.TrimPrefix(imageID, "sha256:")
+		if !found || strings.Contains(ref, "@") || strings.HasPrefix(imageID, strings.TrimPrefix(ref, "sha256:")) {
+			continue
+		}
+		for _, img := range dockerEnv.ImagesInfo {
+			if img.Repository == "<none>" || !imageMatchesReference(ref, img) {
+				continue
+			}
+			if !strings.HasPrefix(imageID, img.ID) {
+				stale = append(stale, StaleTagContainer{Container: cont.Names, Image: ref, RunningID: imageID[:min(12, len(imageID))], CurrentID: img.ID})
+			}
+			break
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.StaleTagContainers = stale
+	})
+	c.dockerMonitor.logger().Info("container image mismatches", "environment", env, "stale", len(stale))
+	return nil
+}
@@ -0,0 +1,79 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// DefaultStartSkewThreshold is the Threshold CheckContainerCreatedVsStarted
+// uses when none is set.
+const DefaultStartSkewThreshold = 24 * time.Hour
+
+type CheckContainerCreatedVsStarted struct {
+	dockerMonitor *DockerMonitor
+	// Threshold is how far apart creation and the last start may be before a
+	// container is listed in StartSkewedContainers.
+	Threshold time.Duration
+}
+
+func (c CheckContainerCreatedVsStarted) Name() string        { return "container-start-skew" }
+func (c CheckContainerCreatedVsStarted) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute sets StartSkewSeconds, the time between a container's creation and
+// its last start, on every container that was started at least once. A
+// large skew means an old container was started again by hand or by a
+// restart policy, which is worth a look during post-incident review.
+func (c CheckContainerCreatedVsStarted) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	threshold := c.Threshold
+	if threshold <= 0 {
+		threshold = DefaultStartSkewThreshold
+	}
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{.Created}} {{.State.StartedAt}}")
+	if err != nil {
+		return err
+	}
+
+	skews := make(map[string]int64)
+	var skewed []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		value, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		rawCreated, rawStarted, _ := strings.Cut(strings.TrimSpace(value), " ")
+		created, err := time.Parse(time.RFC3339Nano, rawCreated)
+		if err != nil {
+			return err
+		}
+		started, err := time.Parse(time.RFC3339Nano, rawStarted)
+		if err != nil {
+			return err
+		}
+		// Containers that never ran report the zero time.
+		if started.Year() <= 1 {
+			continue
+		}
+		skew := started.Sub(created)
+		skews[cont.ID] = int64(skew / time.Second)
+		// A start before creation means the daemon's clock moved.
+		if skew > threshold || skew < -threshold {
+			skewed = append(skewed, cont.Names)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].StartSkewSeconds = skews[e.ContainersInfo[i].ID]
+		}
+		e.StartSkewedContainers = skewed
+	})
+	c.dockerMonitor.logger().Info("container start skew", "environment", env, "threshold", threshold, "skewed", len(skewed))
+	return nil
+}
@@ -0,0 +1,249 @@
+package dockermonitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+)
+
+// UpdateEnvironment applies update to the named environment while holding the
+// monitor's write lock. Actions store their results through it so readers
+// using Snapshot never observe a half-written environment. update must not
+// retain the pointer, nor call back into the monitor.
+func (d *DockerMonitor) UpdateEnvironment(env string, update func(e *DockerEnvironment)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for index := range d.DockerEnvironments {
+		if d.DockerEnvironments[index].Environment == env {
+			update(&d.DockerEnvironments[index])
+		}
+	}
+}
+
+// withEnvironment calls read with the named environment under the read lock.
+// read must not retain the pointer or modify the environment.
+func (d *DockerMonitor) withEnvironment(env string, read func(e *DockerEnvironment)) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for index := range d.DockerEnvironments {
+		if d.DockerEnvironments[index].Environment == env {
+			read(&d.DockerEnvironments[index])
+		}
+	}
+}
+
+// Environment returns a deep copy of the named environment, e.g. for an
+// action to read what earlier actions collected.
+func (d *DockerMonitor) Environment(env string) (DockerEnvironment, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, dockerEnv := range d.DockerEnvironments {
+		if dockerEnv.Environment == env {
+			return dockerEnv.clone(), true
+		}
+	}
+	return DockerEnvironment{}, false
+}
+
+// Snapshot returns a deep copy of the monitor taken under the read lock.
+// Formatters, exporters and handlers read from a snapshot so they see a stable
+// view while workflows keep updating the live monitor. The Redaction policy
+// is applied to the copy.
+func (d *DockerMonitor) Snapshot() *DockerMonitor {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	snapshot := &DockerMonitor{
+		GlobalArgs:        append([]string(nil), d.GlobalArgs...),
+		SSHControlPersist: d.SSHControlPersist,
+		CommandTimeout:    d.CommandTimeout,
+		CommandTimeouts:   maps.Clone(d.CommandTimeouts),
+		Redaction:         d.Redaction,
+	}
+	for _, dockerEnv := range d.DockerEnvironments {
+		dockerEnv = dockerEnv.clone()
+		if d.Redaction != nil {
+			d.Redaction.redactEnvironment(&dockerEnv)
+		}
+		snapshot.DockerEnvironments = append(snapshot.DockerEnvironments, dockerEnv)
+	}
+	return snapshot
+}
+
+// MergeMonitors combines the environments of monitors, e.g. ones collecting
+// different regions in parallel, into a single monitor for reporting. The
+// merged monitor takes its settings from the first monitor. An environment
+// found in several monitors is kept once if its data is identical in all of
+// them and is an error otherwise.
+func MergeMonitors(monitors ...*DockerMonitor) (*DockerMonitor, error) {
+	if len(monitors) == 0 {
+		return &DockerMonitor{}, nil
+	}
+	merged := monitors[0].Snapshot()
+	merged.DockerEnvironments = nil
+	encoded := make(map[string][]byte)
+	for _, m := range monitors {
+		for _, dockerEnv := range m.Snapshot().DockerEnvironments {
+			data, err := json.Marshal(dockerEnv)
+			if err != nil {
+				return nil, err
+			}
+			if previous, found := encoded[dockerEnv.Environment]; found {
+				if !bytes.Equal(previous, data) {
+					return nil, fmt.Errorf("environment %q has conflicting data in different monitors", dockerEnv.Environment)
+				}
+				continue
+			}
+			encoded[dockerEnv.Environment] = data
+			merged.DockerEnvironments = append(merged.DockerEnvironments, dockerEnv)
+		}
+	}
+	return merged, nil
+}
+
+// clone deep-copies every slice, map and pointer field of the environment.
+// New reference-typed fields must be copied here too.
+func (e DockerEnvironment) clone() DockerEnvironment {
+	c := e
+	c.ContainersInfo = make([]ContainerInfo, len(e.ContainersInfo))
+	for i, cont := range e.ContainersInfo {
+		c.ContainersInfo[i] = cont.clone()
+	}
+	c.ImagesInfo = make([]ImageInfo, len(e.ImagesInfo))
+	for i, img := range e.ImagesInfo {
+		c.ImagesInfo[i] = img.clone()
+	}
+	c.StatusReasons = append([]string(nil), e.StatusReasons...)
+	c.UnusedImages = nil
+	for _, img := range e.UnusedImages {
+		c.UnusedImages = append(c.UnusedImages, img.clone())
+	}
+	c.OutdatedImages = append([]OutdatedImage(nil), e.OutdatedImages...)
+	c.ArchMismatches = append([]ArchMismatch(nil), e.ArchMismatches...)
+	c.OversizedLogs = append([]string(nil), e.OversizedLogs...)
+	c.LogDrivers = maps.Clone(e.LogDrivers)
+	c.UnexpectedLogDrivers = append([]string(nil), e.UnexpectedLogDrivers...)
+	c.RootContainers = append([]string(nil), e.RootContainers...)
+	c.WritableRootfsContainers = append([]string(nil), e.WritableRootfsContainers...)
+	c.SharedNetworkContainers = append([]SharedNetwork(nil), e.SharedNetworkContainers...)
+	c.UnboundedMemory = append([]string(nil), e.UnboundedMemory...)
+	c.UnboundedTmpfs = append([]string(nil), e.UnboundedTmpfs...)
+	c.HeavyDiskWriters = append([]string(nil), e.HeavyDiskWriters...)
+	c.LowNofileContainers = append([]string(nil), e.LowNofileContainers...)
+	c.ShortStopTimeouts = append([]string(nil), e.ShortStopTimeouts...)
+	c.StartSkewedContainers = append([]string(nil), e.StartSkewedContainers...)
+	c.UnreachableContainers = append([]string(nil), e.UnreachableContainers...)
+	c.Flapping = append([]string(nil), e.Flapping...)
+	c.ZombieContainers = append([]string(nil), e.ZombieContainers...)
+	c.StaleTagContainers = append([]StaleTagContainer(nil), e.StaleTagContainers...)
+	c.UnmetDependencies = append([]UnmetDependency(nil), e.UnmetDependencies...)
+	c.UnsignedProductionImages = append([]string(nil), e.UnsignedProductionImages...)
+	c.MissingProvenance = append([]string(nil), e.MissingProvenance...)
+	c.Volumes = append([]VolumeInfo(nil), e.Volumes...)
+	c.DanglingVolumes = append([]string(nil), e.DanglingVolumes...)
+	c.SecurityFindings = append([]SecurityFinding(nil), e.SecurityFindings...)
+	c.Tags = append([]string(nil), e.Tags...)
+	c.Builders = nil
+	for _, builder := range e.Builders {
+		builder.Nodes = append([]BuilderNode(nil), builder.Nodes...)
+		c.Builders = append(c.Builders, builder)
+	}
+	c.UnhealthyBuilders = append([]string(nil), e.UnhealthyBuilders...)
+	c.Secrets = append([]SwarmObject(nil), e.Secrets...)
+	c.Configs = append([]SwarmObject(nil), e.Configs...)
+	c.OrphanedSecrets = append([]string(nil), e.OrphanedSecrets...)
+	c.OrphanedConfigs = append([]string(nil), e.OrphanedConfigs...)
+	c.GPUContainers = append([]string(nil), e.GPUContainers...)
+	c.OrphanedContainers = nil
+	for _, cont := range e.OrphanedContainers {
+		c.OrphanedContainers = append(c.OrphanedContainers, cont.clone())
+	}
+	c.PortMismatches = nil
+	for _, m := range e.PortMismatches {
+		m.Expected = append([]string(nil), m.Expected...)
+		m.Actual = append([]string(nil), m.Actual...)
+		m.Unexpected = append([]string(nil), m.Unexpected...)
+		m.Missing = append([]string(nil), m.Missing...)
+		c.PortMismatches = append(c.PortMismatches, m)
+	}
+	c.LabelViolations = nil
+	for _, v := range e.LabelViolations {
+		v.Missing = append([]string(nil), v.Missing...)
+		v.Disallowed = append([]string(nil), v.Disallowed...)
+		c.LabelViolations = append(c.LabelViolations, v)
+	}
+	if e.ComposeDrift != nil {
+		drift := *e.ComposeDrift
+		drift.Missing = append([]string(nil), drift.Missing...)
+		drift.Undeclared = append([]string(nil), drift.Undeclared...)
+		drift.ImageMismatches = append([]ComposeImageMismatch(nil), drift.ImageMismatches...)
+		c.ComposeDrift = &drift
+	}
+	if e.Daemon != nil {
+		daemon := *e.Daemon
+		c.Daemon = &daemon
+	}
+	if e.HostResources != nil {
+		resources := *e.HostResources
+		c.HostResources = &resources
+	}
+	if e.Extensions != nil {
+		c.Extensions = make(map[string]json.RawMessage, len(e.Extensions))
+		for k, v := range e.Extensions {
+			c.Extensions[k] = append(json.RawMessage(nil), v...)
+		}
+	}
+	if e.Capabilities != nil {
+		c.Capabilities = make(map[Capability]bool, len(e.Capabilities))
+		for k, v := range e.Capabilities {
+			c.Capabilities[k] = v
+		}
+	}
+	return c
+}
+
+func (c ContainerInfo) clone() ContainerInfo {
+	cont := c
+	cont.NetworkAddresses = append([]ContainerNetwork(nil), c.NetworkAddresses...)
+	cont.Entrypoint = append([]string(nil), c.Entrypoint...)
+	cont.Cmd = append([]string(nil), c.Cmd...)
+	cont.Ulimits = append([]Ulimit(nil), c.Ulimits...)
+	cont.TmpfsMounts = append([]TmpfsMount(nil), c.TmpfsMounts...)
+	cont.CapAdd = append([]string(nil), c.CapAdd...)
+	cont.CapDrop = append([]string(nil), c.CapDrop...)
+	cont.SecurityOpt = append([]string(nil), c.SecurityOpt...)
+	if c.Logs != nil {
+		logs := *c.Logs
+		cont.Logs = &logs
+	}
+	if c.Stats != nil {
+		stats := *c.Stats
+		cont.Stats = &stats
+	}
+	return cont
+}
+
+func (i ImageInfo) clone() ImageInfo {
+	img := i
+	if i.Provenance != nil {
+		provenance := *i.Provenance
+		img.Provenance = &provenance
+	}
+	return img
+}
+
+// SetExtension stores the JSON encoding of v under key in the environment's
+// Extensions, for custom actions whose results have no dedicated field.
+func (d *DockerMonitor) SetExtension(env, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	d.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		if e.Extensions == nil {
+			e.Extensions = make(map[string]json.RawMessage)
+		}
+		e.Extensions[key] = data
+	})
+	return nil
+}
@@ -0,0 +1,331 @@
+package dockermonitor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultStatsSampleInterval = 2 * time.Second
+
+// ContainerStats holds resource usage of a running container. Cumulative
+// counters come from a single `docker stats` sample, or the cgroup files
+// CheckContainerCgroupStats reads; the *PerSecond rates are only set by
+// CheckContainerNetworkIO, which samples twice.
+type ContainerStats struct {
+	CPUPercent       float64 `json:"cpuPercent"`
+	MemoryUsageBytes int64   `json:"memoryUsageBytes"`
+	MemoryLimitBytes int64   `json:"memoryLimitBytes"`
+	MemoryPercent    float64 `json:"memoryPercent"`
+	NetRxBytes       int64   `json:"netRxBytes"`
+	NetTxBytes       int64   `json:"netTxBytes"`
+	BlockReadBytes   int64   `json:"blockReadBytes"`
+	BlockWriteBytes  int64   `json:"blockWriteBytes"`
+	PIDs             int     `json:"pids"`
+
+	NetRxBytesPerSecond      float64 `json:"netRxBytesPerSecond,omitempty"`
+	NetTxBytesPerSecond      float64 `json:"netTxBytesPerSecond,omitempty"`
+	BlockReadBytesPerSecond  float64 `json:"blockReadBytesPerSecond,omitempty"`
+	BlockWriteBytesPerSecond float64 `json:"blockWriteBytesPerSecond,omitempty"`
+	// DiskWriteBytesPerSecond is the average write rate since the previous
+	// run of CheckContainerDiskWrites.
+	DiskWriteBytesPerSecond float64 `json:"diskWriteBytesPerSecond,omitempty"`
+
+	// CPUUsageMicros is the cumulative CPU time CheckContainerCgroupStats read.
+	CPUUsageMicros int64 `json:"cpuUsageMicros,omitempty"`
+
+	// The moving averages over the last Samples samples, including this one,
+	// set by CheckContainerStats or CheckContainerCgroupStats when
+	// DockerMonitor.StatsWindow is.
+	CPUPercentAvg       float64 `json:"cpuPercentAvg,omitempty"`
+	MemoryUsageBytesAvg int64   `json:"memoryUsageBytesAvg,omitempty"`
+	MemoryPercentAvg    float64 `json:"memoryPercentAvg,omitempty"`
+	Samples             int     `json:"samples,omitempty"`
+}
+
+// sampleStats runs a single `docker stats --no-stream` for the given
+// containers and returns their stats keyed by the requested IDs.
+func (d *DockerMonitor) sampleStats(ctx context.Context, env string, ids []string) (map[string]ContainerStats, error) {
+	stats := make(map[string]ContainerStats)
+	if len(ids) == 0 {
+		return stats, nil
+	}
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	args := append([]string{"stats", "--no-stream", "--format", "{{json .}}"}, ids...)
+	out, err := d.DockerOutput(ctx, env, args...)
+	// Like inspect, stats fails as a whole when a single container vanished.
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		var raw struct {
+			ID       string
+			CPUPerc  string
+			MemUsage string
+			MemPerc  string
+			NetIO    string
+			BlockIO  string
+			PIDs     string
+		}
+		if err := unmarshalLine(line, "stats", &raw); err != nil {
+			return nil, err
+		}
+		s := ContainerStats{
+			CPUPercent:    parsePercent(raw.CPUPerc),
+			MemoryPercent: parsePercent(raw.MemPerc),
+		}
+		s.MemoryUsageBytes, s.MemoryLimitBytes = parseByteSizePair(raw.MemUsage)
+		s.NetRxBytes, s.NetTxBytes = parseByteSizePair(raw.NetIO)
+		s.BlockReadBytes, s.BlockWriteBytes = parseByteSizePair(raw.BlockIO)
+		s.PIDs, _ = strconv.Atoi(raw.PIDs)
+		for _, id := range ids {
+			if strings.HasPrefix(raw.ID, id) {
+				stats[id] = s
+			}
+		}
+	}
+	return stats, nil
+}
+
+func parsePercent(s string) float64 {
+	value, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	return value
+}
+
+// parseByteSizePair parses the "used / total" columns of `docker stats`.
+func parseByteSizePair(s string) (int64, int64) {
+	first, second, _ := strings.Cut(s, "/")
+	return parseByteSize(first), parseByteSize(second)
+}
+
+var byteUnits = map[string]float64{
+	"B":  1,
+	"kB": 1e3, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12,
+	"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40,
+}
+
+// parseByteSize parses docker's human readable sizes such as "1.5kB" or
+// "7.5MiB". Unparsable values yield 0.
+func parseByteSize(s string) int64 {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i <= 0 {
+		return 0
+	}
+	value, err := strconv.ParseFloat(s[:i], 64)
+	multiplier, known := byteUnits[strings.TrimSpace(s[i:])]
+	if err != nil || !known {
+		return 0
+	}
+	return int64(value * multiplier)
+}
+
+// runningContainerIDs lists the IDs of the environment's running containers.
+func runningContainerIDs(e DockerEnvironment) []string {
+	var ids []string
+	for _, cont := range e.ContainersInfo {
+		if cont.State == "running" {
+			ids = append(ids, cont.ID)
+		}
+	}
+	return ids
+}
+
+type CheckContainerStats struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerStats) Name() string        { return "container-stats" }
+func (c CheckContainerStats) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerStats) Execute(ctx context.Context, env string) error {
+	return c.dockerMonitor.dockerStats(ctx, env, c.Name())
+}
+
+// dockerStats stores a `docker stats` sample of env's running containers on
+// behalf of the action source.
+func (d *DockerMonitor) dockerStats(ctx context.Context, env, source string) error {
+	if err := d.requireCapability(env, CapabilityStats); err != nil {
+		return err
+	}
+	dockerEnv, _ := d.Environment(env)
+	stats, err := d.sampleStats(ctx, env, runningContainerIDs(dockerEnv))
+	if err != nil {
+		return err
+	}
+	d.storeStats(env, source, stats)
+	d.logger().Info("container stats", "environment", env, "sampled", len(stats))
+	return nil
+}
+
+// storeStats attaches stats, sampled by the action source, to the matching
+// containers of env. Only container-stats and container-cgroup-stats add to
+// the moving averages, each to its own samples, so a workflow running
+// several stats actions still adds one sample per action and run. What other
+// stats actions derived, their averages and rates, keeps its stored value.
+func (d *DockerMonitor) storeStats(env, source string, stats map[string]ContainerStats) {
+	smoothed := source == "container-stats" || source == "container-cgroup-stats"
+	if smoothed {
+		d.smoothStats(env, source, stats)
+	}
+	d.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			s, found := stats[e.ContainersInfo[i].ID]
+			if !found {
+				continue
+			}
+			if stored := e.ContainersInfo[i].Stats; stored != nil {
+				if !smoothed {
+					s.CPUPercentAvg, s.MemoryUsageBytesAvg, s.MemoryPercentAvg, s.Samples =
+						stored.CPUPercentAvg, stored.MemoryUsageBytesAvg, stored.MemoryPercentAvg, stored.Samples
+				}
+				if source != "container-network-io" {
+					s.NetRxBytesPerSecond, s.NetTxBytesPerSecond = stored.NetRxBytesPerSecond, stored.NetTxBytesPerSecond
+					s.BlockReadBytesPerSecond, s.BlockWriteBytesPerSecond = stored.BlockReadBytesPerSecond, stored.BlockWriteBytesPerSecond
+				}
+				s.DiskWriteBytesPerSecond = stored.DiskWriteBytesPerSecond
+			}
+			e.ContainersInfo[i].Stats = &s
+		}
+	})
+}
+
+// statsSamples is a ring buffer of a container's most recent samples.
+type statsSamples struct {
+	samples []ContainerStats
+	next    int
+}
+
+// smoothStats adds stats to source's samples of env's containers and sets
+// their moving averages, if StatsWindow is set. Containers that weren't
+// sampled this time, e.g. because they stopped, start over.
+func (d *DockerMonitor) smoothStats(env, source string, stats map[string]ContainerStats) {
+	d.statsMu.Lock()
+	defer d.statsMu.Unlock()
+	if d.StatsWindow <= 1 {
+		d.statsSamples = nil
+		return
+	}
+	if d.statsSamples == nil {
+		d.statsSamples = make(map[[3]string]*statsSamples)
+	}
+	for key := range d.statsSamples {
+		if _, found := stats[key[2]]; key[0] == env && key[1] == source && !found {
+			delete(d.statsSamples, key)
+		}
+	}
+
+	for id, s := range stats {
+		key := [3]string{env, source, id}
+		ring := d.statsSamples[key]
+		if ring == nil {
+			ring = &statsSamples{}
+			d.statsSamples[key] = ring
+		}
+		// After a change of StatsWindow the buffer is put back in order,
+		// oldest first, and only keeps the most recent samples.
+		if len(ring.samples) != d.StatsWindow && ring.next != 0 {
+			ring.samples = append(ring.samples[ring.next:], ring.samples[:ring.next]...)
+			ring.next = 0
+		}
+		if len(ring.samples) > d.StatsWindow {
+			ring.samples = ring.samples[len(ring.samples)-d.StatsWindow:]
+		}
+		if len(ring.samples) < d.StatsWindow {
+			ring.samples = append(ring.samples, s)
+		} else {
+			ring.samples[ring.next] = s
+			ring.next = (ring.next + 1) % d.StatsWindow
+		}
+
+		var cpu, memoryPercent float64
+		var memory int64
+		for _, sample := range ring.samples {
+			cpu += sample.CPUPercent
+			memoryPercent += sample.MemoryPercent
+			memory += sample.MemoryUsageBytes
+		}
+		n := len(ring.samples)
+		s.CPUPercentAvg = cpu / float64(n)
+		s.MemoryPercentAvg = memoryPercent / float64(n)
+		s.MemoryUsageBytesAvg = memory / int64(n)
+		s.Samples = n
+		stats[id] = s
+	}
+}
+
+// CheckContainerNetworkIO samples `docker stats` twice, Interval apart, and
+// stores per-second network and block I/O rates, which are easier to chart
+// and alert on than the cumulative counters.
+type CheckContainerNetworkIO struct {
+	dockerMonitor *DockerMonitor
+	Interval      time.Duration
+}
+
+func (c CheckContainerNetworkIO) Name() string        { return "container-network-io" }
+func (c CheckContainerNetworkIO) DependsOn() []string { return []string{"containers-status"} }
+
+func (c CheckContainerNetworkIO) Execute(ctx context.Context, env string) error {
+	if err := c.dockerMonitor.requireCapability(env, CapabilityStats); err != nil {
+		return err
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultStatsSampleInterval
+	}
+
+	// Both samples ask for the same IDs so the deltas compare like with like.
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	ids := runningContainerIDs(dockerEnv)
+	firstAt := time.Now()
+	first, err := c.dockerMonitor.sampleStats(ctx, env, ids)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-time.After(interval):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	secondAt := time.Now()
+	second, err := c.dockerMonitor.sampleStats(ctx, env, ids)
+	if err != nil {
+		return err
+	}
+
+	elapsed := secondAt.Sub(firstAt).Seconds()
+	rates := make(map[string]ContainerStats)
+	for id, s := range second {
+		before, found := first[id]
+		if !found {
+			continue
+		}
+		s.NetRxBytesPerSecond = ratePerSecond(before.NetRxBytes, s.NetRxBytes, elapsed)
+		s.NetTxBytesPerSecond = ratePerSecond(before.NetTxBytes, s.NetTxBytes, elapsed)
+		s.BlockReadBytesPerSecond = ratePerSecond(before.BlockReadBytes, s.BlockReadBytes, elapsed)
+		s.BlockWriteBytesPerSecond = ratePerSecond(before.BlockWriteBytes, s.BlockWriteBytes, elapsed)
+		rates[id] = s
+	}
+
+	c.dockerMonitor.storeStats(env, c.Name(), rates)
+	c.dockerMonitor.logger().Info("container network io", "environment", env, "sampled", len(rates), "interval", interval)
+	return nil
+}
+
+// ratePerSecond turns two counter readings into a rate. Counters reset when a
+// container restarts between samples, which is reported as 0 rather than negative.
+func ratePerSecond(before, after int64, seconds float64) float64 {
+	if after < before || seconds <= 0 {
+		return 0
+	}
+	return float64(after-before) / seconds
+}
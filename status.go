@@ -0,0 +1,102 @@
+package dockermonitor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EnvironmentStatus rolls an environment's findings up into one value.
+type EnvironmentStatus string
+
+const (
+	StatusHealthy  EnvironmentStatus = "healthy"
+	StatusWarning  EnvironmentStatus = "warning"
+	StatusCritical EnvironmentStatus = "critical"
+)
+
+// StatusRules decide an environment's Status. Unhealthy containers make it
+// critical; containers that exited with a non-zero code and, if
+// StoppedThreshold is positive, more stopped containers than that make it a
+// warning.
+type StatusRules struct {
+	StoppedThreshold int `json:"stoppedThreshold,omitempty"`
+}
+
+// ComputeStatus sets Status and StatusReasons of every environment. Call it
+// once all actions ran.
+func (d *DockerMonitor) ComputeStatus(rules StatusRules) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.DockerEnvironments {
+		e := &d.DockerEnvironments[i]
+		e.Status, e.StatusReasons = rules.evaluate(e)
+	}
+}
+
+func (r StatusRules) evaluate(e *DockerEnvironment) (EnvironmentStatus, []string) {
+	status := StatusHealthy
+	var reasons []string
+	raise := func(s EnvironmentStatus, reason string) {
+		if s == StatusCritical || status == StatusHealthy {
+			status = s
+		}
+		reasons = append(reasons, reason)
+	}
+
+	if unhealthy := len(e.UnhealthyContainers()); unhealthy > 0 {
+		raise(StatusCritical, fmt.Sprintf("%d unhealthy containers", unhealthy))
+	}
+	var failed []string
+	for _, cont := range e.ContainersInfo {
+		if code, exited := exitCode(cont); exited && code != 0 {
+			failed = append(failed, cont.Names)
+		}
+	}
+	if len(failed) > 0 {
+		raise(StatusWarning, "exited with an error: "+strings.Join(failed, ", "))
+	}
+	if r.StoppedThreshold > 0 && e.StoppedContainers > r.StoppedThreshold {
+		raise(StatusWarning, fmt.Sprintf("%d stopped containers, more than %d", e.StoppedContainers, r.StoppedThreshold))
+	}
+	return status, reasons
+}
+
+// exitCode reads the code from a docker ps status like "Exited (137) 5 minutes ago".
+func exitCode(cont ContainerInfo) (int, bool) {
+	rest, found := strings.CutPrefix(cont.Status, "Exited (")
+	if !found {
+		return 0, false
+	}
+	code, _, _ := strings.Cut(rest, ")")
+	n, err := strconv.Atoi(code)
+	return n, err == nil
+}
+
+// EnvironmentSummary is the headline view of one environment.
+type EnvironmentSummary struct {
+	Environment string            `json:"environment"`
+	Status      EnvironmentStatus `json:"status,omitempty"`
+	Reasons     []string          `json:"reasons,omitempty"`
+	Running     int               `json:"running"`
+	Stopped     int               `json:"stopped"`
+	Unhealthy   int               `json:"unhealthy"`
+	Images      int               `json:"images"`
+}
+
+// Summary returns the headline numbers and status of every environment.
+func (d *DockerMonitor) Summary() []EnvironmentSummary {
+	var summaries []EnvironmentSummary
+	for _, dockerEnv := range d.Snapshot().DockerEnvironments {
+		summaries = append(summaries, EnvironmentSummary{
+			Environment: dockerEnv.Environment,
+			Status:      dockerEnv.Status,
+			Reasons:     dockerEnv.StatusReasons,
+			Running:     dockerEnv.RunningContainers,
+			Stopped:     dockerEnv.StoppedContainers,
+			Unhealthy:   len(dockerEnv.UnhealthyContainers()),
+			Images:      dockerEnv.TotalLocalDockerImages,
+		})
+	}
+	return summaries
+}
@@ -0,0 +1,84 @@
+package dockermonitor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// DefaultMinStopTimeout is the stop timeout in seconds below which
+// CheckContainerStopTimeout flags a container when MinStopTimeout is zero.
+// It is also what docker waits for containers without one.
+const DefaultMinStopTimeout = 10
+
+type CheckContainerStopTimeout struct {
+	dockerMonitor *DockerMonitor
+	// MinStopTimeout is the shortest acceptable stop timeout in seconds;
+	// DefaultMinStopTimeout when zero.
+	MinStopTimeout int
+}
+
+func (c CheckContainerStopTimeout) Name() string        { return "container-stop-timeout" }
+func (c CheckContainerStopTimeout) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records how long `docker stop` waits for each container to shut
+// down, and with which signal, and lists the running containers that would
+// be killed before they could stop gracefully: those with a stop timeout
+// under the minimum, which includes containers without one when the minimum
+// is above docker's default, and those stopped with SIGKILL.
+func (c CheckContainerStopTimeout) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .Config.StopTimeout}} {{.Config.StopSignal}}")
+	if err != nil {
+		return err
+	}
+
+	minStopTimeout := c.MinStopTimeout
+	if minStopTimeout == 0 {
+		minStopTimeout = DefaultMinStopTimeout
+	}
+	type stopConfig struct {
+		timeout int
+		signal  string
+	}
+	configs := make(map[string]stopConfig)
+	var short []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		raw, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		timeout, signal, _ := strings.Cut(strings.TrimSpace(raw), " ")
+		config := stopConfig{timeout: DefaultMinStopTimeout, signal: "SIGTERM"}
+		if timeout != "null" {
+			if config.timeout, err = strconv.Atoi(timeout); err != nil {
+				return err
+			}
+		}
+		if signal = strings.TrimSpace(signal); signal != "" {
+			config.signal = signal
+		}
+		configs[cont.ID] = config
+		killed := config.signal == "SIGKILL" || config.signal == "KILL" || config.signal == "9"
+		if cont.State == "running" && (config.timeout < minStopTimeout || killed) {
+			short = append(short, cont.Names)
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			if config, found := configs[e.ContainersInfo[i].ID]; found {
+				e.ContainersInfo[i].StopTimeout = config.timeout
+				e.ContainersInfo[i].StopSignal = config.signal
+			}
+		}
+		e.ShortStopTimeouts = short
+	})
+	c.dockerMonitor.logger().Info("container stop timeouts", "environment", env, "short", len(short))
+	return nil
+}
@@ -0,0 +1,135 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+)
+
+// SwarmObject is a Swarm secret or config. Only metadata is collected,
+// never the stored values.
+type SwarmObject struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type CheckSecretsAndConfigs struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckSecretsAndConfigs) Name() string { return "swarm-secrets-configs" }
+
+func (c CheckSecretsAndConfigs) Execute(ctx context.Context, env string) error {
+	if err := c.dockerMonitor.requireCapability(env, CapabilitySwarm); err != nil {
+		return err
+	}
+
+	secrets, err := c.dockerMonitor.listSwarmObjects(ctx, env, "secret")
+	if err != nil {
+		return err
+	}
+	configs, err := c.dockerMonitor.listSwarmObjects(ctx, env, "config")
+	if err != nil {
+		return err
+	}
+	usedSecrets, usedConfigs, err := c.dockerMonitor.serviceReferences(ctx, env)
+	if err != nil {
+		return err
+	}
+	orphanedSecrets := unreferencedSwarmObjects(secrets, usedSecrets)
+	orphanedConfigs := unreferencedSwarmObjects(configs, usedConfigs)
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.Secrets = secrets
+		e.Configs = configs
+		e.OrphanedSecrets = orphanedSecrets
+		e.OrphanedConfigs = orphanedConfigs
+	})
+	c.dockerMonitor.logger().Info("swarm secrets and configs", "environment", env,
+		"secrets", len(secrets), "orphanedSecrets", len(orphanedSecrets),
+		"configs", len(configs), "orphanedConfigs", len(orphanedConfigs))
+	return nil
+}
+
+// listSwarmObjects runs `docker secret ls` or `docker config ls`.
+func (d *DockerMonitor) listSwarmObjects(ctx context.Context, env, kind string) ([]SwarmObject, error) {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := d.DockerOutput(ctx, env, kind, "ls", "--format", "{{json .}}")
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []SwarmObject
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		var object SwarmObject
+		if err := unmarshalLine(line, "SwarmObject", &object); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// serviceReferences returns the secret and config names and IDs mounted by
+// any service.
+func (d *DockerMonitor) serviceReferences(ctx context.Context, env string) (secrets, configs map[string]bool, err error) {
+	secrets = make(map[string]bool)
+	configs = make(map[string]bool)
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := d.DockerOutput(ctx, env, "service", "ls", "--quiet")
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		return secrets, configs, nil
+	}
+
+	args := append([]string{"service", "inspect", "--format", "{{json .Spec.TaskTemplate.ContainerSpec}}"}, ids...)
+	out, err = d.DockerOutput(ctx, env, args...)
+	// Services removed since `service ls` make inspect fail, but the rest are still printed.
+	if err != nil && len(out) == 0 {
+		return nil, nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		// Plugin services have no container spec.
+		if line == "" || line == "null" {
+			continue
+		}
+		var spec struct {
+			Secrets []struct{ SecretID, SecretName string }
+			Configs []struct{ ConfigID, ConfigName string }
+		}
+		if err := unmarshalLine(line, "ContainerSpec", &spec); err != nil {
+			return nil, nil, err
+		}
+		for _, s := range spec.Secrets {
+			secrets[s.SecretID] = true
+			secrets[s.SecretName] = true
+		}
+		for _, c := range spec.Configs {
+			configs[c.ConfigID] = true
+			configs[c.ConfigName] = true
+		}
+	}
+	return secrets, configs, nil
+}
+
+func unreferencedSwarmObjects(objects []SwarmObject, referenced map[string]bool) []string {
+	var names []string
+	for _, object := range objects {
+		if !referenced[object.ID] && !referenced[object.Name] {
+			names = append(names, object.Name)
+		}
+	}
+	return names
+}
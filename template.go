@@ -0,0 +1,60 @@
+package dockermonitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to TemplateFormatter templates next to the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"humanBytes": humanBytes,
+	"join":       strings.Join,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// TemplateFormatter renders a snapshot of the monitor through a text/template,
+// e.g. for markdown reports:
+//
+//	{{range .DockerEnvironments}}## {{.Environment}}
+//	{{range .ContainersInfo}}- {{.Names}} ({{.State}}) {{humanBytes .MemoryLimit}}
+//	{{end}}{{end}}
+//
+// Besides the builtins, templates can call humanBytes, join and json.
+type TemplateFormatter struct {
+	Template *template.Template
+}
+
+// NewTemplateFormatter parses text as a TemplateFormatter template.
+func NewTemplateFormatter(text string) (TemplateFormatter, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return TemplateFormatter{}, err
+	}
+	return TemplateFormatter{Template: tmpl}, nil
+}
+
+func (f TemplateFormatter) Format(w io.Writer, d *DockerMonitor) error {
+	return f.Template.Execute(w, d.Snapshot())
+}
+
+// humanBytes formats a byte count with binary units, e.g. 536870912 as "512MiB".
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value := float64(n)
+	suffix := 0
+	for value >= unit || value <= -unit {
+		value /= unit
+		suffix++
+	}
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.1f", value), "0"), ".") + string("KMGTPE"[suffix-1]) + "iB"
+}
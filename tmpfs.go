@@ -0,0 +1,122 @@
+package dockermonitor
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TmpfsMount is an in-memory mount of a container.
+type TmpfsMount struct {
+	Path string `json:"path"`
+	// Size is the size option as given, e.g. "64m" or "10%"; empty without a limit.
+	Size string `json:"size,omitempty"`
+	// SizeBytes is Size in bytes, 0 when it is relative to the host's memory.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+}
+
+type CheckContainerTmpfsMounts struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckContainerTmpfsMounts) Name() string        { return "container-tmpfs-mounts" }
+func (c CheckContainerTmpfsMounts) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records the tmpfs mounts of every container, from --tmpfs and
+// --mount type=tmpfs alike, and lists those of running containers without a
+// size limit as UnboundedTmpfs: their writes all go to host memory.
+func (c CheckContainerTmpfsMounts) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, `{"tmpfs":{{json .HostConfig.Tmpfs}},"mounts":{{json .HostConfig.Mounts}}}`)
+	if err != nil {
+		return err
+	}
+
+	mounts := make(map[string][]TmpfsMount)
+	var unbounded []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		raw, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		var config struct {
+			Tmpfs  map[string]string `json:"tmpfs"`
+			Mounts []struct {
+				Type         string
+				Target       string
+				TmpfsOptions *struct {
+					SizeBytes int64
+				}
+			} `json:"mounts"`
+		}
+		if err := unmarshalLine(raw, "HostConfig", &config); err != nil {
+			return err
+		}
+
+		var tmpfs []TmpfsMount
+		for path, options := range config.Tmpfs {
+			tmpfs = append(tmpfs, tmpfsMount(path, options))
+		}
+		for _, m := range config.Mounts {
+			if m.Type != "tmpfs" {
+				continue
+			}
+			mount := TmpfsMount{Path: m.Target}
+			if m.TmpfsOptions != nil && m.TmpfsOptions.SizeBytes > 0 {
+				mount.Size = strconv.FormatInt(m.TmpfsOptions.SizeBytes, 10)
+				mount.SizeBytes = m.TmpfsOptions.SizeBytes
+			}
+			tmpfs = append(tmpfs, mount)
+		}
+		sort.Slice(tmpfs, func(i, j int) bool { return tmpfs[i].Path < tmpfs[j].Path })
+		mounts[cont.ID] = tmpfs
+
+		if cont.State != "running" {
+			continue
+		}
+		for _, mount := range tmpfs {
+			if mount.Size == "" {
+				unbounded = append(unbounded, cont.Names+":"+mount.Path)
+			}
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].TmpfsMounts = mounts[e.ContainersInfo[i].ID]
+		}
+		e.UnboundedTmpfs = unbounded
+	})
+	c.dockerMonitor.logger().Info("container tmpfs mounts", "environment", env, "unbounded", len(unbounded))
+	return nil
+}
+
+// tmpfsMount parses the options of a --tmpfs mount, such as
+// "rw,noexec,size=64m". Sizes take the kernel's k, m and g suffixes, or a
+// percentage of the host's memory.
+func tmpfsMount(path, options string) TmpfsMount {
+	mount := TmpfsMount{Path: path}
+	for _, option := range strings.Split(options, ",") {
+		size, found := strings.CutPrefix(option, "size=")
+		if !found || size == "" || size == "0" {
+			continue
+		}
+		mount.Size = size
+		multiplier := int64(1)
+		switch suffix := strings.ToLower(size[len(size)-1:]); suffix {
+		case "k", "m", "g", "t":
+			multiplier = 1 << (10 * (strings.Index("kmgt", suffix) + 1))
+			size = size[:len(size)-1]
+		}
+		if n, err := strconv.ParseInt(size, 10, 64); err == nil {
+			mount.SizeBytes = n * multiplier
+		}
+	}
+	return mount
+}
@@ -0,0 +1,76 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+)
+
+// DefaultMinNofile is the soft nofile limit below which CheckContainerUlimits
+// flags a running container when MinNofile is zero.
+const DefaultMinNofile = 1024
+
+// Ulimit is a resource limit set with `docker run --ulimit`, e.g. "nofile"
+// or "nproc".
+type Ulimit struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+type CheckContainerUlimits struct {
+	dockerMonitor *DockerMonitor
+	// MinNofile is the lowest acceptable soft nofile limit; DefaultMinNofile when zero.
+	MinNofile int64
+}
+
+func (c CheckContainerUlimits) Name() string        { return "container-ulimits" }
+func (c CheckContainerUlimits) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute records the ulimits each container was created with and lists the
+// running containers whose nofile limit is low enough to cause "too many open
+// files" errors under load. Containers without an explicit nofile limit use
+// the daemon's default and aren't flagged.
+func (c CheckContainerUlimits) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+
+	var ids []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		ids = append(ids, cont.ID)
+	}
+	inspected, err := c.dockerMonitor.inspect(ctx, env, ids, "{{json .HostConfig.Ulimits}}")
+	if err != nil {
+		return err
+	}
+
+	minNofile := c.MinNofile
+	if minNofile == 0 {
+		minNofile = DefaultMinNofile
+	}
+	ulimits := make(map[string][]Ulimit)
+	var lowNofile []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		raw, found := inspected[cont.ID]
+		if !found {
+			continue
+		}
+		var limits []Ulimit
+		if err := unmarshalLine(strings.TrimSpace(raw), "Ulimit", &limits); err != nil {
+			return err
+		}
+		ulimits[cont.ID] = limits
+		for _, limit := range limits {
+			if limit.Name == "nofile" && limit.Soft < minNofile && cont.State == "running" {
+				lowNofile = append(lowNofile, cont.Names)
+			}
+		}
+	}
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].Ulimits = ulimits[e.ContainersInfo[i].ID]
+		}
+		e.LowNofileContainers = lowNofile
+	})
+	c.dockerMonitor.logger().Info("container ulimits", "environment", env, "lowNofile", len(lowNofile))
+	return nil
+}
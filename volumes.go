@@ -0,0 +1,84 @@
+package dockermonitor
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// VolumeInfo is a volume with the disk space it uses.
+type VolumeInfo struct {
+	Name       string `json:"name"`
+	Driver     string `json:"driver"`
+	Mountpoint string `json:"mountpoint,omitempty"`
+	// Links counts the containers, running or stopped, using the volume.
+	Links     int   `json:"links"`
+	SizeBytes int64 `json:"sizeBytes"`
+	Dangling  bool  `json:"dangling"`
+}
+
+type CheckVolumeUsage struct {
+	dockerMonitor *DockerMonitor
+}
+
+func (c CheckVolumeUsage) Name() string { return "volume-usage" }
+
+// Execute lists volumes, largest first, with the space they take according
+// to `docker system df -v`. Volumes no container uses are dangling and can be
+// removed with `docker volume prune`.
+func (c CheckVolumeUsage) Execute(ctx context.Context, env string) error {
+
+	// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+	// impact to you machine. Make sure you know the commands you are running.
+	out, err := c.dockerMonitor.DockerOutput(ctx, env, "system", "df", "-v", "--format", "{{json .}}")
+	if err != nil {
+		return err
+	}
+	var usage struct {
+		Volumes []struct {
+			Name       string
+			Driver     string
+			Mountpoint string
+			Links      string
+			Size       string
+		}
+	}
+	if err := json.Unmarshal(out, &usage); err != nil {
+		return err
+	}
+
+	volumes := make([]VolumeInfo, 0, len(usage.Volumes))
+	var dangling []string
+	var danglingBytes int64
+	for _, v := range usage.Volumes {
+		links, _ := strconv.Atoi(v.Links)
+		// Drivers that can't measure their volumes report "N/A", read as 0.
+		volume := VolumeInfo{
+			Name:       v.Name,
+			Driver:     v.Driver,
+			Mountpoint: v.Mountpoint,
+			Links:      links,
+			SizeBytes:  parseByteSize(v.Size),
+			Dangling:   links == 0,
+		}
+		if volume.Dangling {
+			dangling = append(dangling, volume.Name)
+			danglingBytes += volume.SizeBytes
+		}
+		volumes = append(volumes, volume)
+	}
+	sort.SliceStable(volumes, func(i, j int) bool {
+		return volumes[i].SizeBytes > volumes[j].SizeBytes
+	})
+	sort.Strings(dangling)
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		e.Volumes = volumes
+		e.DanglingVolumes = dangling
+		e.DanglingVolumeBytes = danglingBytes
+	})
+	c.dockerMonitor.logger().Info("volume usage", "environment", env, "volumes", len(volumes),
+		"dangling", len(dangling), "danglingBytes", danglingBytes)
+	return nil
+}
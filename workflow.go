@@ -0,0 +1,294 @@
+package dockermonitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Workflow runs a list of actions against the environment named by Name.
+type Workflow struct {
+	Name    string
+	Actions []Action
+
+	// BeforeAction, if set, is called with the action name and environment
+	// right before each action runs.
+	BeforeAction func(action, env string)
+	// AfterAction, if set, is called once each action returns, including the
+	// error it returned (nil on success).
+	AfterAction func(action, env string, err error)
+
+	// Results holds the outcome of every action from the last run.
+	Results []ActionResult
+
+	// Logger receives progress messages; slog.Default() is used when nil.
+	Logger *slog.Logger
+
+	// Resume, if set, records succeeded actions and skips the ones a previous
+	// run already completed for this environment, unless an action still to
+	// run depends on them.
+	Resume *ResumeState
+
+	// Monitor, if set, has its Retries and RetryBudget apply to failed
+	// actions.
+	Monitor *DockerMonitor
+
+	// Progress, if set, is told when ExecuteActions returns, so a run over
+	// many environments can report how far along it is.
+	Progress *Progress
+}
+
+// ExecuteActions runs the workflow's actions in order against its environment,
+// except that a DependentAction always runs after its dependencies. It stops
+// at the first failed action or once ctx is done; unsupported actions, and
+// the actions depending on them, are skipped. When the SSH connection to the
+// environment fails, the remaining actions are recorded as skipped.
+func (w *Workflow) ExecuteActions(ctx context.Context) error {
+	err := w.executeActions(ctx)
+	if w.Progress != nil {
+		w.Progress.Done(w.Name, err)
+	}
+	return err
+}
+
+func (w *Workflow) executeActions(ctx context.Context) error {
+	logger := w.logger()
+	logger.Info("executing workflow", "workflow", w.Name)
+	w.Results = nil
+	actions, err := orderActions(w.Actions)
+	if err != nil {
+		return fmt.Errorf("workflow %s: %w", w.Name, err)
+	}
+	resumed := w.resumedActions(actions)
+	skipped := make(map[string]bool)
+	for i, a := range actions {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if resumed[a.Name()] {
+			w.Results = append(w.Results, ActionResult{
+				Action:      a.Name(),
+				Environment: w.Name,
+				Status:      ActionSkipped,
+				Reason:      "already succeeded in a previous run",
+			})
+			continue
+		}
+		if dependency := skippedDependency(a, skipped); dependency != "" {
+			skipped[a.Name()] = true
+			result := ActionResult{
+				Action:      a.Name(),
+				Environment: w.Name,
+				Status:      ActionSkipped,
+				Reason:      fmt.Sprintf("depends on %s, which was skipped", dependency),
+			}
+			w.Results = append(w.Results, result)
+			logger.Warn("skipped action", "action", a.Name(), "environment", w.Name, "reason", result.Reason)
+			continue
+		}
+		if w.BeforeAction != nil {
+			w.BeforeAction(a.Name(), w.Name)
+		}
+		err := w.execute(ctx, a)
+		if w.AfterAction != nil {
+			w.AfterAction(a.Name(), w.Name, err)
+		}
+
+		// Unsupported actions are skipped so the rest of the workflow still runs.
+		result := newActionResult(a.Name(), w.Name, err)
+		w.Results = append(w.Results, result)
+		if result.Status == ActionSkipped {
+			skipped[a.Name()] = true
+			logger.Warn("skipped action", "action", a.Name(), "environment", w.Name, "reason", result.Reason)
+			continue
+		}
+		if environmentDown(err) {
+			reason := ErrHostUnreachable.Error()
+			if errors.Is(err, ErrDaemonNotRunning) {
+				reason = ErrDaemonNotRunning.Error()
+			}
+			// The other actions would only fail the same way.
+			for _, rest := range actions[i+1:] {
+				w.Results = append(w.Results, ActionResult{
+					Action:      rest.Name(),
+					Environment: w.Name,
+					Status:      ActionSkipped,
+					Reason:      reason,
+				})
+			}
+			logger.Warn(reason+", skipping remaining actions", "environment", w.Name, "skipped", len(actions)-i-1)
+		}
+		if err != nil {
+			return err
+		}
+		if w.Resume != nil {
+			if err := w.Resume.MarkDone(w.Name, a.Name()); err != nil {
+				logger.Warn("saving resume state", "error", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SelectActions returns the actions named by names, together with the
+// actions they depend on, in their order in actions. An unknown name is an
+// error listing the valid ones.
+func SelectActions(actions []Action, names ...string) ([]Action, error) {
+	index := make(map[string]int, len(actions))
+	valid := make([]string, len(actions))
+	for i, a := range actions {
+		index[a.Name()] = i
+		valid[i] = a.Name()
+	}
+	selected := make([]bool, len(actions))
+	var selectAction func(i int)
+	selectAction = func(i int) {
+		if selected[i] {
+			return
+		}
+		selected[i] = true
+		if d, ok := actions[i].(DependentAction); ok {
+			for _, name := range d.DependsOn() {
+				if j, found := index[name]; found {
+					selectAction(j)
+				}
+			}
+		}
+	}
+	for _, name := range names {
+		i, found := index[name]
+		if !found {
+			slices.Sort(valid)
+			return nil, fmt.Errorf("unknown action %q; valid actions are %s", name, strings.Join(valid, ", "))
+		}
+		selectAction(i)
+	}
+	var result []Action
+	for i, a := range actions {
+		if selected[i] {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+// resumedActions returns the names of the ordered actions that succeeded in
+// the previous run and can be skipped. The state records only that they
+// succeeded, not what they collected, so an action that a pending action
+// depends on, directly or not, runs again to collect the data it reads.
+func (w *Workflow) resumedActions(ordered []Action) map[string]bool {
+	resumed := make(map[string]bool)
+	if w.Resume == nil {
+		return resumed
+	}
+	needed := make(map[string]bool)
+	// Dependents come after their dependencies, so walking backwards sees
+	// every action that needs one before the action itself.
+	for i := len(ordered) - 1; i >= 0; i-- {
+		a := ordered[i]
+		if !needed[a.Name()] && w.Resume.Done(w.Name, a.Name()) {
+			resumed[a.Name()] = true
+			continue
+		}
+		if d, ok := a.(DependentAction); ok {
+			for _, name := range d.DependsOn() {
+				needed[name] = true
+			}
+		}
+	}
+	return resumed
+}
+
+// orderActions sorts actions so that each DependentAction comes after the
+// actions it depends on, otherwise keeping their order. Dependencies that
+// aren't in actions are ignored; a dependency cycle is an error.
+func orderActions(actions []Action) ([]Action, error) {
+	index := make(map[string]int, len(actions))
+	for i, a := range actions {
+		index[a.Name()] = i
+	}
+	ordered := make([]Action, 0, len(actions))
+	state := make([]int, len(actions)) // 0 unvisited, 1 visiting, 2 done
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case 1:
+			return fmt.Errorf("action dependency cycle: %s -> %s", strings.Join(path, " -> "), actions[i].Name())
+		case 2:
+			return nil
+		}
+		state[i] = 1
+		path = append(path, actions[i].Name())
+		if d, ok := actions[i].(DependentAction); ok {
+			for _, name := range d.DependsOn() {
+				if j, found := index[name]; found {
+					if err := visit(j); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = 2
+		ordered = append(ordered, actions[i])
+		return nil
+	}
+	for i := range actions {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// skippedDependency returns the first dependency of a that was skipped, or "".
+func skippedDependency(a Action, skipped map[string]bool) string {
+	d, ok := a.(DependentAction)
+	if !ok {
+		return ""
+	}
+	for _, name := range d.DependsOn() {
+		if skipped[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+func (w *Workflow) logger() *slog.Logger {
+	if w.Logger == nil {
+		return slog.Default()
+	}
+	return w.Logger
+}
+
+// execute runs a, retrying failures as configured on w.Monitor.
+func (w *Workflow) execute(ctx context.Context, a Action) error {
+	err := w.executeOnce(ctx, a)
+	if w.Monitor == nil {
+		return err
+	}
+	backoff := retryBackoff
+	for attempt := 1; attempt <= w.Monitor.Retries && retryable(ctx, err); attempt++ {
+		if !w.Monitor.RetryBudget.allow() {
+			w.logger().Warn("retry budget exhausted, not retrying", "action", a.Name(), "environment", w.Name)
+			break
+		}
+		w.logger().Warn("retrying action", "action", a.Name(), "environment", w.Name, "attempt", attempt, "error", err)
+		if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+			break
+		}
+		backoff *= 2
+		err = w.executeOnce(ctx, a)
+	}
+	return err
+}
+
+func (w *Workflow) executeOnce(ctx context.Context, a Action) error {
+	return asActionError(w.Name, a.Name(), a.Execute(ctx, w.Name))
+}
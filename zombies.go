@@ -0,0 +1,100 @@
+package dockermonitor
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+type CheckZombieProcesses struct {
+	dockerMonitor *DockerMonitor
+	history       *zombieHistory
+}
+
+// zombieHistory holds the previous zombie count of every container, keyed
+// by environment and container ID.
+type zombieHistory struct {
+	mu     sync.Mutex
+	counts map[[2]string]int
+}
+
+func (c CheckZombieProcesses) Name() string        { return "zombie-processes" }
+func (c CheckZombieProcesses) DependsOn() []string { return []string{"containers-status"} }
+
+// Execute counts the zombie (defunct) processes in every running container
+// with `docker top`. Zombies pile up when the container's PID 1 doesn't reap
+// its children, which `docker run --init` or tini fixes; a count that keeps
+// growing between runs of the action is logged as a warning.
+func (c CheckZombieProcesses) Execute(ctx context.Context, env string) error {
+	dockerEnv, _ := c.dockerMonitor.Environment(env)
+	previous := make(map[string]int)
+	c.history.mu.Lock()
+	for key, count := range c.history.counts {
+		if key[0] == env {
+			previous[key[1]] = count
+		}
+	}
+	c.history.mu.Unlock()
+
+	zombies := make(map[string]int)
+	var withZombies []string
+	for _, cont := range dockerEnv.ContainersInfo {
+		if cont.State != "running" {
+			continue
+		}
+		// WARNING:: please be careful of what commands you run. Running arbitrary commands can cause unexpected
+		// impact to you machine. Make sure you know the commands you are running.
+		out, err := c.dockerMonitor.DockerOutput(ctx, env, "top", cont.ID, "-eo", "pid,stat")
+		if environmentDown(err) {
+			return err
+		}
+		if err != nil {
+			// The container may have stopped since it was listed.
+			c.dockerMonitor.logger().Warn("listing container processes", "environment", env, "container", cont.Names, "error", err)
+			continue
+		}
+		count := countZombies(string(out))
+		zombies[cont.ID] = count
+		if count == 0 {
+			continue
+		}
+		withZombies = append(withZombies, cont.Names)
+		if count > previous[cont.ID] && previous[cont.ID] > 0 {
+			c.dockerMonitor.logger().Warn("zombie processes growing, is an init process missing?", "environment", env,
+				"container", cont.Names, "before", previous[cont.ID], "now", count)
+		}
+	}
+
+	c.history.mu.Lock()
+	for key := range c.history.counts {
+		if key[0] == env {
+			delete(c.history.counts, key)
+		}
+	}
+	for id, count := range zombies {
+		c.history.counts[[2]string{env, id}] = count
+	}
+	c.history.mu.Unlock()
+
+	c.dockerMonitor.UpdateEnvironment(env, func(e *DockerEnvironment) {
+		for i := range e.ContainersInfo {
+			e.ContainersInfo[i].Zombies = zombies[e.ContainersInfo[i].ID]
+		}
+		e.ZombieContainers = withZombies
+	})
+	c.dockerMonitor.logger().Info("zombie processes", "environment", env, "containers", len(withZombies))
+	return nil
+}
+
+// countZombies counts the processes whose STAT starts with Z in `docker top
+// -eo pid,stat` output.
+func countZombies(out string) int {
+	count := 0
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	for _, line := range lines[1:] {
+		if fields := strings.Fields(line); len(fields) >= 2 && strings.HasPrefix(fields[1], "Z") {
+			count++
+		}
+	}
+	return count
+}